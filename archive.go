@@ -0,0 +1,451 @@
+package json_configs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config bundles
+// - A file ending in .gz is decompressed, and decoded by the extension before it, such as fan.json.gz
+// - An archive (.zip, .tar, .tar.gz or .tgz) is a virtual directory, where each member is a config file
+// - Members are named with ArchiveSeparator, such as "bundle.zip!devices/fan.json"
+const ArchiveSeparator = "!"
+
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// Decompressed content is limited to MaxDecompressedSize bytes, for each gzip file or archive,
+// so a decompression bomb can't exhaust memory
+var MaxDecompressedSize int64 = 256 << 20
+
+// Check if a file is an archive, by suffix
+func isArchive(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split an archive member name, such as "bundle.zip!devices/fan.json", into archive and member
+func splitMember(name string) (archive, member string, ok bool) {
+	for i := strings.Index(name, ArchiveSeparator); i >= 0; {
+		if isArchive(name[:i]) {
+			return name[:i], name[i+len(ArchiveSeparator):], true
+		}
+		next := strings.Index(name[i+1:], ArchiveSeparator)
+		if next < 0 {
+			break
+		}
+		i += 1 + next
+	}
+	return
+}
+
+// Check if a name is a gzip file, not a compressed archive
+func isGzip(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".gz") && !isArchive(name)
+}
+
+// A fileSystem that decompresses .gz files, and reads archive members
+// - Each archive is read once, and kept in memory as an fs.FS
+type archiveFileSystem struct {
+	fileSystem
+	archiveMap map[string]fsFileSystem
+}
+
+// Add gzip and archive support to fsys, if it doesn't have it already
+func withArchives(fsys fileSystem) fileSystem {
+	if _, ok := fsys.(*archiveFileSystem); ok {
+		return fsys
+	}
+	return &archiveFileSystem{fileSystem: fsys, archiveMap: make(map[string]fsFileSystem)}
+}
+
+// Open an archive as an fs.FS
+// - A zip file is an fs.FS, and tar members are read into an in-memory fs.FS
+func (a *archiveFileSystem) archive(name string) (archive fsFileSystem, err error) {
+	var b []byte
+	var fullpath string
+	var r io.Reader
+	var header *tar.Header
+	var ok bool
+
+	if fullpath, err = a.fileSystem.abs(name); err != nil {
+		return
+	}
+	if archive, ok = a.archiveMap[fullpath]; ok {
+		return
+	}
+	if b, err = a.fileSystem.readFile(name); err != nil {
+		return
+	}
+
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		var zr *zip.Reader
+		var size uint64
+		if zr, err = zip.NewReader(bytes.NewReader(b), int64(len(b))); err != nil {
+			err = fmt.Errorf("reading archive: %v [%s]", err, name)
+			return
+		}
+		// Members are decompressed as read, and the zip reader checks each against its size
+		for _, file := range zr.File {
+			if size += file.UncompressedSize64; size > uint64(MaxDecompressedSize) {
+				err = fmt.Errorf("reading archive: exceeds %d bytes decompressed [%s]", MaxDecompressedSize, name)
+				return
+			}
+		}
+		archive = fsFileSystem{fsys: zr}
+	} else {
+		r = bytes.NewReader(b)
+		if !strings.HasSuffix(lower, ".tar") {
+			if r, err = gzip.NewReader(r); err != nil {
+				err = fmt.Errorf("reading archive: %v [%s]", err, name)
+				return
+			}
+		}
+		members := make(memFS)
+		limited := &io.LimitedReader{R: r, N: MaxDecompressedSize + 1}
+		tr := tar.NewReader(limited)
+		for {
+			var file *memFile
+			member := "."
+			if header, err = tr.Next(); err == nil {
+				member = path.Clean(strings.TrimPrefix(header.Name, "/"))
+				if header.Typeflag == tar.TypeReg && fs.ValidPath(member) && member != "." {
+					file = &memFile{name: path.Base(member), mode: fs.FileMode(header.Mode).Perm(), modTime: header.ModTime}
+					file.data, err = ioutil.ReadAll(tr)
+				}
+			}
+			if limited.N <= 0 {
+				err = fmt.Errorf("reading archive: exceeds %d bytes decompressed [%s]", MaxDecompressedSize, name)
+				return
+			} else if err == io.EOF {
+				err = nil
+				break
+			} else if err != nil {
+				err = fmt.Errorf("reading archive: %v [%s]", err, name)
+				return
+			}
+			if file != nil {
+				members[member] = file
+			}
+		}
+		archive = fsFileSystem{fsys: members}
+	}
+	a.archiveMap[fullpath] = archive
+	return
+}
+
+// List the config files in an archive, those with a decoder, in lexical order
+func (a *archiveFileSystem) members(name string) (members []string, err error) {
+	var archive fsFileSystem
+
+	if archive, err = a.archive(name); err != nil {
+		return
+	}
+	err = fs.WalkDir(archive.fsys, ".", func(member string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if member == "." {
+			return nil
+		}
+		if isIgnored(entry.Name()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if _, ok := decoderByExt(member); ok && !entry.IsDir() {
+			members = append(members, name+ArchiveSeparator+member)
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("reading archive: %v [%s]", err, name)
+		return
+	}
+	sort.Strings(members)
+	return
+}
+
+func (a *archiveFileSystem) abs(name string) (fullpath string, err error) {
+	if archive, member, ok := splitMember(name); ok {
+		if fullpath, err = a.fileSystem.abs(archive); err != nil {
+			return
+		}
+		if member, err = (fsFileSystem{}).name(member); err != nil {
+			return
+		}
+		return fullpath + ArchiveSeparator + member, nil
+	}
+	return a.fileSystem.abs(name)
+}
+
+func (a *archiveFileSystem) stat(name string) (info fs.FileInfo, err error) {
+	var archive fsFileSystem
+
+	if archiveName, member, ok := splitMember(name); ok {
+		if archive, err = a.archive(archiveName); err != nil {
+			return
+		}
+		return archive.stat(member)
+	}
+	return a.fileSystem.stat(name)
+}
+
+func (a *archiveFileSystem) readFile(name string) (b []byte, err error) {
+	var archive fsFileSystem
+
+	if archiveName, member, ok := splitMember(name); ok {
+		if archive, err = a.archive(archiveName); err != nil {
+			return
+		}
+		b, err = archive.readFile(member)
+	} else {
+		b, err = a.fileSystem.readFile(name)
+	}
+	if err == nil && isGzip(name) {
+		var r io.ReadCloser
+		if r, err = gzip.NewReader(bytes.NewReader(b)); err != nil {
+			err = &fs.PathError{Op: "gunzip", Path: name, Err: err}
+			return
+		}
+		defer r.Close()
+		if b, err = ioutil.ReadAll(&sizeLimitReader{r: r, remaining: MaxDecompressedSize}); err != nil {
+			err = &fs.PathError{Op: "gunzip", Path: name, Err: err}
+		}
+	}
+	return
+}
+
+func (a *archiveFileSystem) open(name string) (r io.ReadCloser, err error) {
+	var archive fsFileSystem
+
+	if archiveName, member, ok := splitMember(name); ok {
+		if archive, err = a.archive(archiveName); err != nil {
+			return
+		}
+		r, err = archive.open(member)
+	} else {
+		r, err = a.fileSystem.open(name)
+	}
+	if err == nil && isGzip(name) {
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(r); err != nil {
+			r.Close()
+			err = &fs.PathError{Op: "gunzip", Path: name, Err: err}
+			return
+		}
+		r = gzipReadCloser{Reader: &sizeLimitReader{r: zr, remaining: MaxDecompressedSize}, gzip: zr, file: r}
+	}
+	return
+}
+
+// Close a gzip reader and the file it reads
+type gzipReadCloser struct {
+	io.Reader
+	gzip *gzip.Reader
+	file io.Closer
+}
+
+func (g gzipReadCloser) Close() error {
+	g.gzip.Close()
+	return g.file.Close()
+}
+
+// Reader that fails once more than 'remaining' bytes are read, rather than truncating like io.LimitReader
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (n int, err error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err = l.r.Read(p)
+	if l.remaining -= int64(n); l.remaining < 0 {
+		return n, fmt.Errorf("exceeds %d bytes decompressed", MaxDecompressedSize)
+	}
+	return
+}
+
+func (a *archiveFileSystem) glob(pattern string) (matches []string, err error) {
+	var archive fsFileSystem
+
+	if archiveName, member, ok := splitMember(pattern); ok {
+		if archive, err = a.archive(archiveName); err != nil {
+			return
+		}
+		if matches, err = archive.glob(member); err != nil {
+			return
+		}
+		for i := range matches {
+			matches[i] = archiveName + ArchiveSeparator + matches[i]
+		}
+		return
+	}
+	return a.fileSystem.glob(pattern)
+}
+
+// Walk a directory, or the members of an archive
+// - The root of an archive is named with a trailing ArchiveSeparator, such as "bundle.zip!"
+func (a *archiveFileSystem) walkDir(root string, fn fs.WalkDirFunc) (err error) {
+	var archive fsFileSystem
+	var info fs.FileInfo
+
+	archiveName, member, ok := splitMember(root)
+	if !ok && isArchive(root) {
+		if info, err = a.fileSystem.stat(root); err == nil && !info.IsDir() {
+			archiveName, member, ok = root, ".", true
+		}
+	}
+	if !ok {
+		return a.fileSystem.walkDir(root, fn)
+	}
+	if archive, err = a.archive(archiveName); err != nil {
+		return
+	}
+	return archive.walkDir(member, func(name string, entry fs.DirEntry, walkErr error) error {
+		if name == "." {
+			return fn(archiveName+ArchiveSeparator, entry, walkErr)
+		}
+		return fn(archiveName+ArchiveSeparator+name, entry, walkErr)
+	})
+}
+
+func (a *archiveFileSystem) isAbs(name string) bool {
+	if archive, _, ok := splitMember(name); ok {
+		return a.fileSystem.isAbs(archive)
+	}
+	return a.fileSystem.isAbs(name)
+}
+
+func (a *archiveFileSystem) join(elem ...string) string {
+	if len(elem) > 0 {
+		if archive, member, ok := splitMember(elem[0]); ok {
+			return archive + ArchiveSeparator + path.Join(append([]string{member}, elem[1:]...)...)
+		}
+	}
+	return a.fileSystem.join(elem...)
+}
+
+// Split a member's full path after the archive's directory, so its distinct name includes the archive
+func (a *archiveFileSystem) split(name string) (dir, file string) {
+	if archive, member, ok := splitMember(name); ok {
+		dir, file = a.fileSystem.split(archive)
+		return dir, file + ArchiveSeparator + member
+	}
+	return a.fileSystem.split(name)
+}
+
+func (a *archiveFileSystem) dir(name string) string {
+	if archive, member, ok := splitMember(name); ok {
+		if member = path.Dir(member); member == "." {
+			member = ""
+		}
+		return archive + ArchiveSeparator + member
+	}
+	return a.fileSystem.dir(name)
+}
+
+func (a *archiveFileSystem) base(name string) string {
+	if _, member, ok := splitMember(name); ok {
+		return path.Base(member)
+	}
+	return a.fileSystem.base(name)
+}
+
+// In-memory fs.FS of tar members, by path
+// - Directories are implied by member paths
+type memFS map[string]*memFile
+
+// A member, or an implied directory, as its own fs.FileInfo and fs.DirEntry
+type memFile struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f *memFile) Name() string               { return f.name }
+func (f *memFile) Size() int64                { return int64(len(f.data)) }
+func (f *memFile) Mode() fs.FileMode          { return f.mode }
+func (f *memFile) ModTime() time.Time         { return f.modTime }
+func (f *memFile) IsDir() bool                { return f.mode.IsDir() }
+func (f *memFile) Sys() interface{}           { return nil }
+func (f *memFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f *memFile) Info() (fs.FileInfo, error) { return f, nil }
+
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if file, ok := m[name]; ok {
+		return &memHandle{file: file, r: bytes.NewReader(file.data)}, nil
+	}
+	if _, err := m.ReadDir(name); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memHandle{file: &memFile{name: path.Base(name), mode: fs.ModeDir | 0555}, r: bytes.NewReader(nil)}, nil
+}
+
+// List a directory, in lexical order, with a directory entry for each subdirectory
+func (m memFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	var member, rest, prefix string
+	var file *memFile
+
+	if name != "." {
+		prefix = name + "/"
+	}
+	dirs := make(map[string]bool)
+	for member, file = range m {
+		if !strings.HasPrefix(member, prefix) {
+			continue
+		}
+		rest = member[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			if !dirs[rest[:i]] {
+				dirs[rest[:i]] = true
+				entries = append(entries, &memFile{name: rest[:i], mode: fs.ModeDir | 0555})
+			}
+			continue
+		}
+		entries = append(entries, file)
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return
+}
+
+// An open member or directory
+type memHandle struct {
+	file *memFile
+	r    *bytes.Reader
+}
+
+func (h *memHandle) Stat() (fs.FileInfo, error) { return h.file, nil }
+func (h *memHandle) Read(p []byte) (int, error) {
+	if h.file.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: h.file.name, Err: fs.ErrInvalid}
+	}
+	return h.r.Read(p)
+}
+func (h *memHandle) Close() error { return nil }
@@ -0,0 +1,177 @@
+package json_configs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type archiveMember struct {
+	name    string
+	content string
+}
+
+var archiveMembers = []archiveMember{
+	{"devices/fan.json", `{"name": "Fan", "port": 1}`},
+	{"lamp.yaml", "name: Lamp\nport: 2\n"},
+	{"README.md", "not a config"},
+	{".hidden.json", `{"name": "Hidden"}`},
+}
+
+func zipBytes(t *testing.T, members []archiveMember) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, member := range members {
+		w, err := zw.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(member.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarBytes(t *testing.T, members []archiveMember) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, member := range members {
+		if err := tw.WriteHeader(&tar.Header{Name: member.name, Mode: 0644, Size: int64(len(member.content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(member.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchives(t *testing.T) {
+	type archiveDevice struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	tarred := tarBytes(t, append(archiveMembers, archiveMember{"../escape.json", `{"name": "Escape"}`}))
+	fsys := fstest.MapFS{
+		"bundle.zip":    {Data: zipBytes(t, archiveMembers)},
+		"bundle.tar":    {Data: tarred},
+		"bundle.tar.gz": {Data: gzipBytes(t, tarred)},
+		"bundle.tgz":    {Data: gzipBytes(t, tarred)},
+		"fan.json.gz":   {Data: gzipBytes(t, []byte(`{"name": "Fan", "port": 1}`))},
+		"bad.zip":       {Data: []byte("not a zip")},
+		"bad.json.gz":   {Data: []byte("not gzip")},
+		"conflict.zip":  {Data: zipBytes(t, []archiveMember{{"fan.json", `{"name": "Fan", "port": 3}`}})},
+	}
+	both := ResultMap{"Fan": archiveDevice{Name: "Fan", Port: 1}, "Lamp": archiveDevice{Name: "Lamp", Port: 2}}
+
+	tests := []struct {
+		name    string
+		files   []string
+		maxSize int64
+		result  ResultMap
+		err     string
+	}{
+		{name: "zip", files: []string{"bundle.zip"}, result: both},
+		{name: "tar", files: []string{"bundle.tar"}, result: both},
+		{name: "tar.gz", files: []string{"bundle.tar.gz"}, result: both},
+		{name: "tgz", files: []string{"bundle.tgz"}, result: both},
+		{name: "gzip file", files: []string{"fan.json.gz"}, result: ResultMap{"Fan": archiveDevice{Name: "Fan", Port: 1}}},
+		{name: "member", files: []string{"bundle.tgz!lamp.yaml"}, result: ResultMap{"Lamp": archiveDevice{Name: "Lamp", Port: 2}}},
+		{
+			name:  "member in messages",
+			files: []string{"bundle.zip", "conflict.zip"},
+			err:   `"1" [bundle.zip!devices/fan.json]`,
+		},
+		{name: "missing member", files: []string{"bundle.zip!missing.json"}, err: "no such file [bundle.zip!missing.json]"},
+		{name: "bad zip", files: []string{"bad.zip"}, err: "reading archive: zip: not a valid zip file [bad.zip]"},
+		{name: "bad gzip", files: []string{"bad.json.gz"}, err: "gunzip bad.json.gz"},
+		{name: "zip size limit", files: []string{"bundle.zip"}, maxSize: 50, err: "reading archive: exceeds 50 bytes decompressed [bundle.zip]"},
+		{name: "tar size limit", files: []string{"bundle.tar.gz"}, maxSize: 50, err: "reading archive: exceeds 50 bytes decompressed [bundle.tar.gz]"},
+		{name: "gzip size limit", files: []string{"fan.json.gz"}, maxSize: 10, err: "exceeds 10 bytes"},
+		{name: "within size limit", files: []string{"fan.json.gz"}, maxSize: 26, result: ResultMap{"Fan": archiveDevice{Name: "Fan", Port: 1}}},
+	}
+
+	defer func(maxSize int64) { MaxDecompressedSize = maxSize }(MaxDecompressedSize)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var device archiveDevice
+			MaxDecompressedSize = 256 << 20
+			if test.maxSize > 0 {
+				MaxDecompressedSize = test.maxSize
+			}
+			resultMap, err := ReadConfigFilesFS(fsys, &device, "Name", test.files...)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(resultMap, test.result) {
+				t.Errorf("got %+v, want %+v", resultMap, test.result)
+			}
+		})
+	}
+}
+
+func TestArchiveMembers(t *testing.T) {
+	tarred := tarBytes(t, append(archiveMembers, archiveMember{"../escape.json", `{}`}, archiveMember{"/abs.json", `{}`}))
+	a := withArchives(fsFileSystem{fsys: fstest.MapFS{
+		"bundle.zip": {Data: zipBytes(t, archiveMembers)},
+		"bundle.tar": {Data: tarred},
+	}}).(*archiveFileSystem)
+
+	tests := []struct {
+		archive string
+		members []string
+	}{
+		{"bundle.zip", []string{"bundle.zip!devices/fan.json", "bundle.zip!lamp.yaml"}},
+		{"bundle.tar", []string{"bundle.tar!abs.json", "bundle.tar!devices/fan.json", "bundle.tar!lamp.yaml"}},
+	}
+	for _, test := range tests {
+		members, err := a.members(test.archive)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.archive, err)
+			continue
+		}
+		sort.Strings(members)
+		if !reflect.DeepEqual(members, test.members) {
+			t.Errorf("%s: got %q, want %q", test.archive, members, test.members)
+		}
+	}
+
+	for name, want := range map[string][3]string{
+		"bundle.zip!devices/fan.json": {"bundle.zip", "devices/fan.json", "true"},
+		"dir/b.tar.gz!x!y.json":       {"dir/b.tar.gz", "x!y.json", "true"},
+		"wow!/fan.json":               {"", "", "false"},
+		"a!b.zip!fan.json":            {"a!b.zip", "fan.json", "true"},
+		"bundle.zip":                  {"", "", "false"},
+	} {
+		archive, member, ok := splitMember(name)
+		if archive != want[0] || member != want[1] || (ok && want[2] != "true") || (!ok && want[2] != "false") {
+			t.Errorf("splitMember(%s): got %q %q %v, want %q", name, archive, member, ok, want)
+		}
+	}
+}
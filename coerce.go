@@ -0,0 +1,103 @@
+package json_configs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lenient coercions, enabled with LenientCoercion
+// - Booleans accept yes/no and on/off, as well as strconv.ParseBool() values
+// - Duration fields accept a number, in the unit from a `unit:"s"` tag
+// ByteSize fields always accept a size with a suffix, such as "64MiB"
+
+// ByteSize is a number of bytes, configured as a number or a string with a suffix
+// - Decimal suffixes KB, MB, GB, TB are powers of 1000, binary suffixes KiB, MiB, GiB, TiB are powers of 1024
+type ByteSize int64
+
+// Byte size units, by lower case suffix
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// Parse a byte size such as "512", "64MiB" or "1.5 GB"
+func ParseByteSize(s string) (size ByteSize, err error) {
+	var f float64
+
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok || i == 0 {
+		err = fmt.Errorf("invalid byte size %q", s)
+		return
+	}
+	f, err = strconv.ParseFloat(s[:i], 64)
+	if err != nil || f*unit > math.MaxInt64 {
+		err = fmt.Errorf("invalid byte size %q", s)
+		return
+	}
+	size = ByteSize(f * unit)
+	return
+}
+
+// Format a byte size with the largest binary suffix that divides it exactly
+func (size ByteSize) String() string {
+	var suffix string
+
+	for _, suffix = range []string{"TiB", "GiB", "MiB", "KiB"} {
+		unit := ByteSize(byteSizeUnits[strings.ToLower(suffix)])
+		if size != 0 && size%unit == 0 {
+			return fmt.Sprintf("%d%s", size/unit, suffix)
+		}
+	}
+	return strconv.FormatInt(int64(size), 10)
+}
+
+// Parse a boolean, also accepting yes/no and on/off
+func parseLenientBool(s string) (b bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// Parse a number as a duration, in a unit such as "s" or "ms"
+func parseDurationUnit(s, unit string) (dur time.Duration, err error) {
+	var f float64
+	var unitDur time.Duration
+
+	unitDur, err = time.ParseDuration("1" + unit)
+	if err != nil {
+		err = fmt.Errorf("invalid unit %q", unit)
+		return
+	}
+	f, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return
+	}
+	dur = time.Duration(f * float64(unitDur))
+	return
+}
@@ -0,0 +1,76 @@
+package json_configs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVDecoder parses CSV and TSV config files, such as a spreadsheet of devices
+// - The header row names the keys, and each following row is an element
+// - Element positions are row numbers, the line each row starts on, so messages name the row
+// - Empty cells are absent, so other files can set those settings
+// - Values are strings, converted to each field's type, even with StrictTypes
+type CSVDecoder struct {
+	Comma rune
+}
+
+func (d CSVDecoder) Decode(b []byte) (doc Document, err error) {
+	var header, record []string
+	var elements []interface{}
+	var line, position, i int
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+	if d.Comma != 0 {
+		r.Comma = d.Comma
+	}
+	r.LazyQuotes = r.Comma == '\t'
+
+	if header, err = r.Read(); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("csv: missing header row")
+		}
+		return
+	}
+	seen := make(map[string]bool)
+	for i = range header {
+		header[i] = strings.TrimSpace(header[i])
+		if len(header[i]) == 0 {
+			err = fmt.Errorf("csv: header column %d is empty", i+1)
+			return
+		}
+		if seen[header[i]] {
+			err = fmt.Errorf("csv: header column %s is repeated", header[i])
+			return
+		}
+		seen[header[i]] = true
+	}
+
+	elements = []interface{}{}
+	for {
+		if record, err = r.Read(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		position, _ = r.FieldPos(0)
+		doc.Positions = append(doc.Positions, position)
+
+		element := make(map[string]interface{})
+		for i = range record {
+			if len(strings.TrimSpace(record[i])) == 0 {
+				continue
+			}
+			element[header[i]] = record[i]
+			line, _ = r.FieldPos(i)
+			doc.setLine(position, header[i], line)
+		}
+		elements = append(elements, element)
+	}
+	doc.Config = elements
+	doc.Untyped = true
+	return
+}
@@ -1,5 +1,10 @@
 package json_configs
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Parse JSON config files into a data object using reflect package
 // - Can configure an application using one or more JSON files
 // - For example, put general settings in one file, credentials in a second file.
@@ -7,11 +12,49 @@ package json_configs
 // Parsed{} contains each JSON element, remembering where it was found
 // - DistinctName is shortest unique name across all filenames
 // - Position is element # within the file: 0 if single element, 1...N if array of N elements
+// - Layer is index of the config layer the file was listed in: 0 if not layered
+// - InheritedFrom is the element Id the settings were inherited from: "" if the element's own settings
+// - IncludedFrom is the chain of files that included this file: empty if listed directly
+// - Lines is the line number of each key in the file, when known
+// - Untyped is set if the settings are all strings, from a format such as INI or CSV
 type Parsed struct {
-	FileName     string
-	DistinctName string
-	Position     int
-	ElementMap   ElementMap
+	FileName      string
+	DistinctName  string
+	Position      int
+	Layer         int
+	InheritedFrom string
+	IncludedFrom  []string
+	ElementMap    ElementMap
+	Lines         map[string]int
+	Untyped       bool
+}
+
+// Location of the element for messages, such as "fan.json" or "credentials.json:elem#1 (inherited from *)"
+// - Included files also show the include chain, such as "common/net.json (included from fan.json)"
+func (parsed Parsed) Location() string {
+	return parsed.location(0)
+}
+
+// Location of a key in the element, such as "fan.json:4", if its line is known
+func (parsed Parsed) KeyLocation(key string) string {
+	return parsed.location(parsed.Lines[key])
+}
+
+func (parsed Parsed) location(line int) (location string) {
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d", parsed.DistinctName, line)
+	} else if parsed.Position == 0 {
+		location = parsed.DistinctName
+	} else {
+		location = fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
+	}
+	if len(parsed.IncludedFrom) > 0 {
+		location = fmt.Sprintf("%s (included from %s)", location, strings.Join(parsed.IncludedFrom, " -> "))
+	}
+	if len(parsed.InheritedFrom) > 0 {
+		location = fmt.Sprintf("%s (inherited from %s)", location, parsed.InheritedFrom)
+	}
+	return
 }
 
 // ElementMap is the JSON element parsed into a key-value map
@@ -21,5 +64,13 @@ type ElementMap map[string]interface{}
 // - This element Id is used as the ParsedMap key (so becomes a required field)
 type ParsedMap map[string][]Parsed
 
+// Settings for the WildcardId apply to every element Id, unless the element sets them itself
+// - For example, an element {"name": "*", "host": "192.168.0.10"} sets host for all devices
+const WildcardId = "*"
+
+// An element with the ExtendsKey inherits all settings of the element Id it names
+// - For example, {"name": "Fan2", "extends": "Fan", "device_id": "A4"}
+const ExtendsKey = "extends"
+
 // The Parsed map form is then collapsed into a single data object result per Id
 type ResultMap map[string]interface{}
@@ -0,0 +1,217 @@
+package json_configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Config files are parsed by a Decoder, chosen by file extension, or by sniffing the content
+// - Each decoder produces the same structure as JSON, so settings merge and conflict across formats
+// - For example, fan.yaml and credentials.json can be read in one ReadConfigFiles() call
+
+// Document is a decoded config file
+// - Config is map[string]interface{} for a single element, or []interface{} of elements,
+// with values of type string, float64, bool, nil, []interface{} or map[string]interface{}
+// - Lines is the line number of each key, by element position, as in Parsed{}
+// - Ids is an element Id by position, for formats that name elements, such as INI sections
+// - Positions is the position of each element in an array, if not 1...N, such as the rows of a CSV file
+// - Untyped is set by formats whose values are all strings, such as INI and CSV, so StrictTypes doesn't apply
+type Document struct {
+	Config    interface{}
+	Lines     map[int]map[string]int
+	Ids       map[int]string
+	Positions []int
+	Untyped   bool
+}
+
+// Decoder parses the content of a config file
+type Decoder interface {
+	Decode(b []byte) (doc Document, err error)
+}
+
+// Element is an element decoded from a config file
+// - Position is 0 for a single element, otherwise its position in the file, as in Parsed{}
+// - Lines is the line number of each key, and Id is set by formats that name elements
+// - Untyped is set for elements of an untyped Document
+type Element struct {
+	Position int
+	Value    interface{}
+	Lines    map[string]int
+	Id       string
+	Untyped  bool
+}
+
+// StreamDecoder decodes a config file one element at a time, so a large file isn't read into memory at once
+type StreamDecoder interface {
+	Decoder
+	DecodeStream(r io.Reader, emit func(element Element)) error
+}
+
+// Decoders by file extension
+var decoderMap = map[string]Decoder{
+	".json":   JSONDecoder{},
+	".jsonc":  JSONDecoder{Lenient: true},
+	".json5":  JSONDecoder{Lenient: true},
+	".yaml":   YAMLDecoder{},
+	".yml":    YAMLDecoder{},
+	".toml":   TOMLDecoder{},
+	".ini":    INIDecoder{},
+	".jsonl":  JSONLinesDecoder{},
+	".ndjson": JSONLinesDecoder{},
+	".csv":    CSVDecoder{Comma: ','},
+	".tsv":    CSVDecoder{Comma: '\t'},
+
+	KeyPerFileSuffix: keyPerFileDecoder{},
+}
+
+// Content of other files is sniffed, trying each decoder in turn
+// - INI is not sniffed, as [section] lines also read as TOML tables, so an INI file needs its extension
+var sniffDecoders = []Decoder{JSONDecoder{}, TOMLDecoder{}, YAMLDecoder{}}
+
+// Register a decoder for files with extension 'ext', such as ".conf", replacing any existing decoder
+func RegisterDecoder(ext string, decoder Decoder) {
+	decoderMap[strings.ToLower(ext)] = decoder
+}
+
+// Decoder for a file by extension, longest first, so ".merge.json" could be registered separately from ".json"
+// - A gzip file is decoded by the extension before .gz
+func decoderByExt(filename string) (decoder Decoder, ok bool) {
+	var ext string
+
+	name := strings.ToLower(filename)
+	if isGzip(name) {
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		ext = name[i:]
+		if strings.ContainsAny(ext, "/\\") {
+			continue
+		}
+		if decoder, ok = decoderMap[ext]; ok {
+			return
+		}
+	}
+	return
+}
+
+// Decode a config file, by extension or sniffing the content
+// - If no decoder recognizes the content, the error is from parsing as JSON
+func decodeConfig(filename string, b []byte) (doc Document, err error) {
+	var decoder Decoder
+	var ok bool
+
+	if decoder, ok = decoderByExt(filename); ok {
+		return decoder.Decode(b)
+	}
+
+	for _, decoder = range sniffDecoders {
+		if doc, err = decoder.Decode(b); err != nil {
+			continue
+		}
+		switch doc.Config.(type) {
+		case map[string]interface{}, []interface{}:
+			return
+		}
+	}
+	return JSONDecoder{}.Decode(b)
+}
+
+// Decode the elements of a config file in fsys, calling emit for each, streaming if its decoder can
+// - A streamed file can fail after some elements are emitted, so the caller discards them to skip the file
+// - Errors name the file
+func decodeElements(fsys fileSystem, filename string, emit func(element Element)) (err error) {
+	var b []byte
+	var doc Document
+	var r io.ReadCloser
+
+	if decoder, ok := decoderByExt(filename); ok {
+		if stream, ok := decoder.(StreamDecoder); ok {
+			if r, err = fsys.open(filename); err != nil {
+				err = fmt.Errorf("reading file: %v", err)
+				return
+			}
+			defer r.Close()
+			if err = stream.DecodeStream(r, emit); err != nil {
+				err = fmt.Errorf("%v, skipping [%s]", err, filename)
+			}
+			return
+		}
+	}
+
+	if b, err = fsys.readFile(filename); err != nil {
+		err = fmt.Errorf("reading file: %v", err)
+		return
+	}
+	if doc, err = decodeConfig(filename, b); err != nil {
+		err = fmt.Errorf("%v, skipping [%s]", err, filename)
+		return
+	}
+	if err = doc.emit(emit); err != nil {
+		err = fmt.Errorf("parsing config: %v [%s]", err, filename)
+	}
+	return
+}
+
+// Call emit for each element of a document, a single element or an array
+func (doc Document) emit(emit func(element Element)) (err error) {
+	var position int
+
+	switch config := doc.Config.(type) {
+	case map[string]interface{}:
+		emit(Element{Value: config, Lines: doc.Lines[0], Id: doc.Ids[0], Untyped: doc.Untyped})
+	case []interface{}:
+		for i, v := range config {
+			position = i + 1
+			if i < len(doc.Positions) {
+				position = doc.Positions[i]
+			}
+			emit(Element{Position: position, Value: v, Lines: doc.Lines[position], Id: doc.Ids[position], Untyped: doc.Untyped})
+		}
+	default:
+		k := "null"
+		if config != nil {
+			k = reflect.TypeOf(config).Kind().String()
+		}
+		err = fmt.Errorf("unrecognized JSON type %q", k)
+	}
+	return
+}
+
+// JSONDecoder parses JSON config files
+// - If Lenient, or LenientJSON is set, comments, trailing commas, unquoted keys and single-quoted strings
+// are accepted, and syntax errors give the line in the original file
+type JSONDecoder struct {
+	Lenient bool
+}
+
+func (d JSONDecoder) Decode(b []byte) (doc Document, err error) {
+	lenient := d.Lenient || LenientJSON
+	if lenient {
+		b = lenientToJSON(b)
+	}
+	if err = json.Unmarshal(b, &doc.Config); err != nil {
+		if lenient {
+			err = syntaxErrorLine(b, err)
+		}
+		return
+	}
+	doc.Lines = keyLines(b)
+	return
+}
+
+// Record the line of a key of the element at 'position'
+func (doc *Document) setLine(position int, key string, line int) {
+	if doc.Lines == nil {
+		doc.Lines = make(map[int]map[string]int)
+	}
+	if doc.Lines[position] == nil {
+		doc.Lines[position] = make(map[string]int)
+	}
+	doc.Lines[position][key] = line
+}
@@ -0,0 +1,99 @@
+package json_configs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A decoder test case, checking the decoded config, or an error containing 'err'
+// - lines and ids are checked if set
+type decoderTest struct {
+	name   string
+	input  string
+	config interface{}
+	lines  map[int]map[string]int
+	ids    map[int]string
+	err    string
+}
+
+func runDecoderTests(t *testing.T, decoder Decoder, tests []decoderTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := decoder.Decode([]byte(test.input))
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Config, test.config) {
+				t.Errorf("config:\n got  %#v\n want %#v", doc.Config, test.config)
+			}
+			if test.lines != nil && !reflect.DeepEqual(doc.Lines, test.lines) {
+				t.Errorf("lines:\n got  %v\n want %v", doc.Lines, test.lines)
+			}
+			if test.ids != nil && !reflect.DeepEqual(doc.Ids, test.ids) {
+				t.Errorf("ids:\n got  %v\n want %v", doc.Ids, test.ids)
+			}
+		})
+	}
+}
+
+func TestDecoderByExt(t *testing.T) {
+	tests := []struct {
+		filename string
+		decoder  Decoder
+		ok       bool
+	}{
+		{"fan.json", JSONDecoder{}, true},
+		{"FAN.JSON", JSONDecoder{}, true},
+		{"fan.jsonc", JSONDecoder{Lenient: true}, true},
+		{"fan.yml", YAMLDecoder{}, true},
+		{"fan.toml", TOMLDecoder{}, true},
+		{"fan.ini", INIDecoder{}, true},
+		{"fan.json.gz", JSONDecoder{}, true},
+		{"bundle.zip!devices/fan.yaml", YAMLDecoder{}, true},
+		{"config.d/fan", nil, false},
+		{"fan.conf", nil, false},
+	}
+	for _, test := range tests {
+		decoder, ok := decoderByExt(test.filename)
+		if ok != test.ok || !reflect.DeepEqual(decoder, test.decoder) {
+			t.Errorf("%s: got %#v %v, want %#v %v", test.filename, decoder, ok, test.decoder, test.ok)
+		}
+	}
+}
+
+func TestDecodeConfigSniffing(t *testing.T) {
+	tests := []struct {
+		input  string
+		config interface{}
+	}{
+		{`{"name": "Fan"}`, map[string]interface{}{"name": "Fan"}},
+		{"name = \"Fan\"\nport = 21000\n", map[string]interface{}{"name": "Fan", "port": 21000.0}},
+		{"name: Fan\nport: 21000\n", map[string]interface{}{"name": "Fan", "port": 21000.0}},
+		{"[Fan]\nport = 21000\n", map[string]interface{}{"Fan": map[string]interface{}{"port": 21000.0}}},
+	}
+	for _, test := range tests {
+		doc, err := decodeConfig("fan", []byte(test.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(doc.Config, test.config) {
+			t.Errorf("%q:\n got  %#v\n want %#v", test.input, doc.Config, test.config)
+		}
+	}
+
+	// Content no decoder recognizes is reported as JSON, including INI, which needs its extension
+	for _, input := range []string{"{name", "[Fan]\nhost = 10.0.0.1\n"} {
+		if _, err := decodeConfig("fan", []byte(input)); err == nil || !strings.Contains(err.Error(), "invalid character") {
+			t.Errorf("%q: expected JSON error, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package json_configs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Options for listing config files in a directory
+// - Include and Exclude are globs, matched against the path relative to the directory if they contain "/",
+// otherwise against the base name, such as "*.json" or "devices/*.json"
+// - Hidden files and directories, and editor backup files, are always ignored
+type DirOptions struct {
+	Recursive bool     // Also list files in subdirectories
+	Include   []string // Files to list, any file with a decoder or an archive if none
+	Exclude   []string // Files and subdirectories not to list, even if included
+}
+
+// Editor backup and swap files, by suffix
+var backupSuffixes = []string{"~", ".bak", ".orig", ".swp", ".swo", ".tmp"}
+
+// Read config files in a directory into a map of structs, where 'data' points to struct and idName is field for map key
+// - Files are listed by DirFilenames(), so ReadConfigFiles() reports any duplicates
+func ReadConfigDir(data interface{}, idName string, dir string, opts DirOptions) (resultMap ResultMap, err error) {
+	var filenames []string
+
+	fsys := withArchives(osFileSystem{})
+	if filenames, err = listDir(fsys, dir, opts); err != nil {
+		return
+	}
+	return readConfigFiles("ReadConfigDir", fsys, data, idName, [][]string{filenames})
+}
+
+// Read config files in a directory of fsys, such as an embed.FS, into a map of structs
+func ReadConfigDirFS(fsys fs.FS, data interface{}, idName string, dir string, opts DirOptions) (resultMap ResultMap, err error) {
+	var filenames []string
+
+	dirFS := withArchives(fsFileSystem{fsys: fsys})
+	if filenames, err = listDir(dirFS, dir, opts); err != nil {
+		return
+	}
+	return readConfigFiles("ReadConfigDirFS", dirFS, data, idName, [][]string{filenames})
+}
+
+// List config files in a directory, in lexical order, for DistinctFilenames() or ReadConfigFiles()
+// - Returns an error if the directory can't be read, or no files are found
+func DirFilenames(dir string, opts DirOptions) (filenames []string, err error) {
+	return listDir(osFileSystem{}, dir, opts)
+}
+
+func listDir(fsys fileSystem, dir string, opts DirOptions) (filenames []string, err error) {
+	fsys = withArchives(fsys)
+	include := opts.Include
+
+	// Check patterns before walking, so a bad pattern isn't silently unmatched
+	for _, pattern := range append(include[:len(include):len(include)], opts.Exclude...) {
+		if _, err = path.Match(pattern, ""); err != nil {
+			err = fmt.Errorf("pattern %s invalid, %v [%s]", pattern, err, dir)
+			return
+		}
+	}
+
+	// The directory itself is walked first, as root of the relative paths
+	root := ""
+	err = fsys.walkDir(dir, func(filename string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if len(root) == 0 {
+			root = filename
+			if !entry.IsDir() {
+				return fmt.Errorf("not a directory")
+			}
+			return nil
+		}
+		// The root of an archive is "bundle.zip!", with member names following it
+		rel := strings.TrimPrefix(filename, root)
+		if !strings.HasSuffix(root, ArchiveSeparator) {
+			var relErr error
+			if rel, relErr = filepath.Rel(root, filename); relErr != nil {
+				return relErr
+			}
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if !opts.Recursive || isIgnored(entry.Name()) || matchAny(opts.Exclude, rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isIgnored(entry.Name()) || matchAny(opts.Exclude, rel) {
+			return nil
+		}
+		if len(include) == 0 {
+			// Without patterns, list each file that has a decoder, or is an archive of them
+			if _, ok := decoderByExt(entry.Name()); !ok && !isArchive(entry.Name()) {
+				return nil
+			}
+		} else if !matchAny(include, rel) {
+			return nil
+		}
+		filenames = append(filenames, filename)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("reading directory: %v [%s]", err, dir)
+		return
+	}
+	if len(filenames) == 0 {
+		err = fmt.Errorf("no config files found in directory [%s]", dir)
+	}
+	return
+}
+
+// Check if a file or directory is hidden, or an editor backup file
+func isIgnored(name string) bool {
+	if strings.HasPrefix(name, ".") || (strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")) {
+		return true
+	}
+	for _, suffix := range backupSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a slash-separated relative path matches any pattern
+// - A pattern without "/" matches the base name
+func matchAny(patterns []string, rel string) bool {
+	var name string
+
+	for _, pattern := range patterns {
+		name = rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package json_configs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestListDir(t *testing.T) {
+	fsys := fsFileSystem{fsys: fstest.MapFS{
+		"configs/fan.json":           {Data: []byte(`{}`)},
+		"configs/lamp.yaml":          {Data: []byte(`{}`)},
+		"configs/heater.toml":        {Data: []byte(`{}`)},
+		"configs/pump.ini":           {Data: []byte(`{}`)},
+		"configs/sensors.csv":        {Data: []byte(`{}`)},
+		"configs/events.jsonl":       {Data: []byte(`{}`)},
+		"configs/old.json.gz":        {Data: []byte(`{}`)},
+		"configs/bundle.zip":         {Data: []byte(`{}`)},
+		"configs/fan.merge.json":     {Data: []byte(`{}`)},
+		"configs/README.md":          {Data: []byte(`{}`)},
+		"configs/notes.txt":          {Data: []byte(`{}`)},
+		"configs/.hidden.json":       {Data: []byte(`{}`)},
+		"configs/fan.json~":          {Data: []byte(`{}`)},
+		"configs/devices/desk.json":  {Data: []byte(`{}`)},
+		"configs/devices/test-1.yml": {Data: []byte(`{}`)},
+		"configs/drafts/new.json":    {Data: []byte(`{}`)},
+		"configs/.git/config.json":   {Data: []byte(`{}`)},
+		"empty/notes.txt":            {Data: []byte(`{}`)},
+	}}
+
+	tests := []struct {
+		name  string
+		dir   string
+		opts  DirOptions
+		files []string
+		err   string
+	}{
+		{
+			name: "default decoders and archives",
+			dir:  "configs",
+			files: []string{"configs/bundle.zip", "configs/events.jsonl", "configs/fan.json", "configs/fan.merge.json",
+				"configs/heater.toml", "configs/lamp.yaml", "configs/old.json.gz", "configs/pump.ini", "configs/sensors.csv"},
+		},
+		{
+			name:  "include",
+			dir:   "configs",
+			opts:  DirOptions{Include: []string{"*.json", "*.txt"}},
+			files: []string{"configs/fan.json", "configs/fan.merge.json", "configs/notes.txt"},
+		},
+		{
+			name:  "recursive with exclude",
+			dir:   "configs",
+			opts:  DirOptions{Recursive: true, Include: []string{"*.json", "*.yml"}, Exclude: []string{"drafts", "devices/test-*", "*.merge.json"}},
+			files: []string{"configs/devices/desk.json", "configs/fan.json"},
+		},
+		{
+			name: "no config files",
+			dir:  "empty",
+			err:  "no config files found in directory [empty]",
+		},
+		{
+			name: "bad pattern",
+			dir:  "configs",
+			opts: DirOptions{Include: []string{"[json"}},
+			err:  "pattern [json invalid",
+		},
+		{
+			name: "not a directory",
+			dir:  "configs/fan.json",
+			err:  "reading directory: not a directory [configs/fan.json]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := listDir(fsys, test.dir, test.opts)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(files, test.files) {
+				t.Errorf("got %q, want %q", files, test.files)
+			}
+		})
+	}
+}
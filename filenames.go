@@ -2,8 +2,8 @@ package json_configs
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
 )
 
 type FileDetail struct {
@@ -17,66 +17,100 @@ const MAX_ITERATIONS = 100
 
 // Create Parsed{} struct for each filename, validating and formatting names
 func DistinctFilenames(filenames []string, errList *[]string) (fileDetails []FileDetail) {
+	return distinctFilenames(osFileSystem{}, filenames, errList)
+}
+
+// Create Parsed{} struct for each filename in fsys, such as an embed.FS
+// - Names are relative to the root of fsys, and FullPath is rooted at "/", such as "/devices/fan.json"
+func DistinctFilenamesFS(fsys fs.FS, filenames []string, errList *[]string) (fileDetails []FileDetail) {
+	return distinctFilenames(fsFileSystem{fsys: fsys}, filenames, errList)
+}
+
+func distinctFilenames(fsys fileSystem, filenames []string, errList *[]string) (fileDetails []FileDetail) {
 	var err error
 	var file FileDetail
-	var items, newitems, remainitems []string
-	var filename, fullpath, dir, name string
+	var filename string
 	var i int
-	var ok, distinct bool
+	var ok bool
 
-	// Map to determine distinct names
-	usednames := make(map[string][]string)
+	fsys = withArchives(fsys)
 
 	// Map to make sure full-path names are unique
-	fullnameMap := make(map[string]FileDetail)
+	fullnameMap := make(map[string]int)
 
 	for _, filename = range filenames {
 
 		// Make sure each filename is a valid file
-		fullpath, err = ValidateFile(filename)
+		file, err = newFileDetail(fsys, filename)
 		if err != nil {
 			*errList = append(*errList, fmt.Sprintf("%v", err))
 			continue
 		}
 
 		// Check for duplicates
-		file, ok = fullnameMap[fullpath]
+		i, ok = fullnameMap[file.FullPath]
 		if ok {
-			*errList = append(*errList, fmt.Sprintf("file is duplicate of %s, skipping [%s]", file.Name, filename))
+			*errList = append(*errList, fmt.Sprintf("file is duplicate of %s, skipping [%s]", fileDetails[i].Name, filename))
 			continue
 		}
+		fullnameMap[file.FullPath] = len(fileDetails)
+		fileDetails = append(fileDetails, file)
+	}
+	return distinctNames(fsys, fileDetails)
+}
 
-		// Split base from dir to create initial FileDetail
-		dir, name = filepath.Split(fullpath)
-		if len(name) == 0 {
-			*errList = append(*errList, fmt.Sprintf("invalid file [%s]", err, filename))
-			continue
-		}
-		file = FileDetail{
-			Name:         filename,
-			DistinctName: name,
-			FullPath:     fullpath,
-		}
+// Validate a file, returning its FileDetail with the base name as DistinctName
+func newFileDetail(fsys fileSystem, filename string) (file FileDetail, err error) {
+	var fullpath, dir, name string
+	var i int
 
-		// Make list of file directory components in absolute path
-		for i = 0; i < MAX_ITERATIONS; i++ {
-			dir = filepath.Dir(dir)
-			name = filepath.Base(dir)
-			file.PathComponents = append(file.PathComponents, name)
-			if len(dir) == 1 {
-				break
-			}
+	if fullpath, err = validateFile(fsys, filename); err != nil {
+		return
+	}
+
+	// Split base from dir to create initial FileDetail
+	dir, name = fsys.split(fullpath)
+	if len(name) == 0 {
+		err = fmt.Errorf("invalid file [%s]", filename)
+		return
+	}
+	file = FileDetail{
+		Name:         filename,
+		DistinctName: name,
+		FullPath:     fullpath,
+	}
+
+	// Make list of file directory components in absolute path
+	for i = 0; i < MAX_ITERATIONS; i++ {
+		dir = fsys.dir(dir)
+		name = fsys.base(dir)
+		file.PathComponents = append(file.PathComponents, name)
+		if len(dir) == 1 {
+			break
 		}
-		fullnameMap[fullpath] = file
+	}
+	return
+}
+
+// Prepend directory components to DistinctName of each file, until names are distinct, keeping the order of files
+func distinctNames(fsys fileSystem, files []FileDetail) (fileDetails []FileDetail) {
+	var file FileDetail
+	var items, newitems, remainitems []string
+	var name, fullpath string
+	var i int
+	var ok, distinct bool
+
+	// Map to determine distinct names
+	usednames := make(map[string][]string)
+
+	// Map of files by full path, updated with distinct names
+	fullnameMap := make(map[string]FileDetail)
+
+	for _, file = range files {
+		fullnameMap[file.FullPath] = file
 
 		// List base names used, to see if names are distinct
-		items, ok = usednames[file.DistinctName]
-		if ok {
-			items = append(items, fullpath)
-		} else {
-			items = []string{fullpath}
-		}
-		usednames[file.DistinctName] = items
+		usednames[file.DistinctName] = append(usednames[file.DistinctName], file.FullPath)
 	}
 
 	for i = 0; i < MAX_ITERATIONS; i++ {
@@ -96,7 +130,7 @@ func DistinctFilenames(filenames []string, errList *[]string) (fileDetails []Fil
 					}
 					if len(file.PathComponents) > 0 {
 						// prepend distinct name with first path component from list
-						file.DistinctName = filepath.Join(file.PathComponents[0], file.DistinctName)
+						file.DistinctName = fsys.join(file.PathComponents[0], file.DistinctName)
 						file.PathComponents = file.PathComponents[1:]
 
 						// store new distinct name
@@ -130,22 +164,33 @@ func DistinctFilenames(filenames []string, errList *[]string) (fileDetails []Fil
 		}
 	}
 
-	// Copy results from fullnameMap
-	for _, file = range fullnameMap {
-		fileDetails = append(fileDetails, file)
+	// Copy results from fullnameMap, in the order files were listed
+	for _, file = range files {
+		fileDetails = append(fileDetails, fullnameMap[file.FullPath])
 	}
 	return
 }
 
+// Check that a file exists and is not a directory, returning its full path
 func ValidateFile(file string) (fullpath string, err error) {
+	return validateFile(osFileSystem{}, file)
+}
+
+// Check that a file exists in fsys and is not a directory, returning its full path rooted at "/"
+func ValidateFileFS(fsys fs.FS, file string) (fullpath string, err error) {
+	return validateFile(fsFileSystem{fsys: fsys}, file)
+}
+
+func validateFile(fsys fileSystem, file string) (fullpath string, err error) {
 	var dirInfo os.FileInfo
 
-	fullpath, err = filepath.Abs(file)
+	fsys = withArchives(fsys)
+	fullpath, err = fsys.abs(file)
 	if err != nil {
 		err = fmt.Errorf("%v [%s]", err, file)
 		return
 	}
-	dirInfo, err = os.Stat(fullpath)
+	dirInfo, err = fsys.stat(fullpath)
 	if os.IsNotExist(err) {
 		err = fmt.Errorf("invalid, no such file [%s]", file)
 		return
@@ -0,0 +1,236 @@
+package json_configs
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Bind data object fields to command-line flags
+// - Flag name is the json tag name, or the field name if there is no tag
+// - Flag help text is taken from the `usage:"..."` tag
+// - Flags explicitly set on the command-line take precedence over file values, see ApplyFlags()
+
+// flagValue holds the command-line value for a data object field
+type flagValue struct {
+	param reflect.StructField
+	value string
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+// Set is called by flag.Parse(), so a badly formatted value is reported as a command error
+func (f *flagValue) Set(s string) (err error) {
+	_, err = decodeParam(f.param, s)
+	if err != nil {
+		return
+	}
+	f.value = s
+	return
+}
+
+// Allow bool fields to be set as -flag, without a value
+func (f *flagValue) IsBoolFlag() bool {
+	t := f.param.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name() == "bool"
+}
+
+// Register a flag on FlagSet 'fs' for each field of the struct 'data' points to
+func BindFlags(fs *flag.FlagSet, data interface{}) {
+	var err error
+	var usage string
+	var i int
+
+	// Make sure data is a pointer to a struct
+	k := reflect.TypeOf(data).Kind().String()
+	if k != "ptr" {
+		err = fmt.Errorf("BindFlags: 'data' must be ptr, not %s", k)
+		panic(err)
+	}
+	st := reflect.TypeOf(data).Elem()
+
+	params, names := flagFields(st)
+	for i = range params {
+		usage = params[i].Tag.Get("usage")
+		if len(usage) == 0 {
+			usage = fmt.Sprintf("Override setting %s", params[i].Name)
+		}
+		fs.Var(&flagValue{param: params[i]}, names[i], usage)
+	}
+}
+
+// Fields of data object (st) that have a flag, with the flag name of each
+func flagFields(st reflect.Type) (params []reflect.StructField, names []string) {
+	var name string
+	var i int
+
+	for i = 0; i < st.NumField(); i++ {
+		param := st.Field(i)
+		if len(param.PkgPath) > 0 {
+			// unexported field
+			continue
+		}
+		name = keyName(param)
+		if _, removed := param.Tag.Lookup("removed"); removed || name == "-" || tagOption(param, "remain") {
+			continue
+		}
+		params = append(params, param)
+		names = append(names, name)
+	}
+	return
+}
+
+// Set fields of the struct 'data' points to, from flags explicitly set on the command-line
+// - Call after ReadConfigFile(), so flags override settings from files
+func ApplyFlags(fs *flag.FlagSet, data interface{}) (err error) {
+	// Make sure data is a pointer to a struct
+	k := reflect.TypeOf(data).Kind().String()
+	if k != "ptr" {
+		err = fmt.Errorf("ApplyFlags: 'data' must be ptr, not %s", k)
+		panic(err)
+	}
+	return applyFlags(fs, reflect.ValueOf(data).Elem(), "")
+}
+
+// Set fields of 'sv' from flags explicitly set, except the field named 'skip'
+func applyFlags(fs *flag.FlagSet, sv reflect.Value, skip string) (err error) {
+	var errList []string
+
+	st := sv.Type()
+
+	// Visit only flags that were set
+	fs.Visit(func(f *flag.Flag) {
+		fv, ok := f.Value.(*flagValue)
+		if !ok || fv.param.Name == skip {
+			return
+		}
+		param, ok := st.FieldByName(fv.param.Name)
+		if !ok {
+			return
+		}
+		rv, err := decodeParam(param, fv.value)
+		if err != nil {
+			errList = append(errList, fmt.Sprintf("flag -%s invalid, parameter %s: %v", f.Name, param.Name, err))
+			return
+		}
+		sv.FieldByIndex(param.Index).Set(rv)
+	})
+
+	if len(errList) > 0 {
+		err = fmt.Errorf("%s", strings.Join(errList, "\n"))
+	}
+	return
+}
+
+// Read the config directory given with -c into a map of structs, with per-field overrides from the command-line
+// - 'data' points to struct and idName is field for map key, as for ReadConfigDir()
+// - Overrides apply to one element, selected by the flag for idName, such as -name Fan -host 10.0.0.1
+// - Without that flag, overrides apply only if the directory has a single element
+// - Returns a nil resultMap for a command-line error, otherwise any config error as ReadConfigDir()
+func ParseCommandLine(data interface{}, idName string) (resultMap ResultMap, err error) {
+	var configDir, idFlag string
+	var dirInfo os.FileInfo
+	var set bool
+	var i int
+
+	// Make sure data is a pointer to a struct
+	k := reflect.TypeOf(data).Kind().String()
+	if k != "ptr" {
+		err = fmt.Errorf("ParseCommandLine: 'data' must be ptr, not %s", k)
+		panic(err)
+	}
+	st := reflect.TypeOf(data).Elem()
+
+	// A field flag can't replace -c, or another flag already defined
+	params, names := flagFields(st)
+	for i = range params {
+		if names[i] == "c" {
+			err = fmt.Errorf("flag -c for parameter %s clashes with option -c <directory>", params[i].Name)
+			return
+		}
+		if flag.CommandLine.Lookup(names[i]) != nil {
+			err = fmt.Errorf("flag -%s for parameter %s is already defined", names[i], params[i].Name)
+			return
+		}
+		if params[i].Name == idName {
+			idFlag = names[i]
+		}
+	}
+
+	// Command-line arguments
+	flag.StringVar(&configDir, "c", "", "Directory of JSON configs")
+	BindFlags(flag.CommandLine, data)
+	flag.Parse()
+
+	// Validate
+	if len(configDir) == 0 {
+		err = fmt.Errorf("option -c <directory> required")
+		return
+	}
+	dirInfo, err = os.Stat(configDir)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("-c %s %v", configDir, err)
+		return
+	} else if err == nil && !dirInfo.IsDir() {
+		err = fmt.Errorf("-c %s is not a directory", configDir)
+		return
+	} else if err != nil {
+		return
+	}
+
+	resultMap, err = ReadConfigDir(data, idName, configDir, DirOptions{})
+	if resultMap == nil {
+		resultMap = make(ResultMap)
+	}
+
+	// Find the element to override, if any flags are set
+	elementId := ""
+	flag.CommandLine.Visit(func(f *flag.Flag) {
+		if _, ok := f.Value.(*flagValue); !ok {
+			return
+		}
+		if f.Name == idFlag {
+			elementId = f.Value.String()
+		}
+		set = true
+	})
+	if !set {
+		return
+	}
+	if len(elementId) == 0 {
+		if len(resultMap) != 1 {
+			err = fmt.Errorf("flags override a single element, select one of %d with -%s", len(resultMap), idFlag)
+			resultMap = nil
+			return
+		}
+		for elementId = range resultMap {
+		}
+	}
+	v, ok := resultMap[elementId]
+	if !ok {
+		err = fmt.Errorf("flag -%s %s matches no element in %s", idFlag, elementId, configDir)
+		resultMap = nil
+		return
+	}
+
+	// Apply overrides to a copy of the element
+	ptr := reflect.New(st)
+	ptr.Elem().Set(reflect.ValueOf(v))
+	if flagErr := applyFlags(flag.CommandLine, ptr.Elem(), idName); flagErr != nil {
+		err = flagErr
+		resultMap = nil
+		return
+	}
+	resultMap[elementId] = ptr.Elem().Interface()
+	return
+}
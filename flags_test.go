@@ -0,0 +1,215 @@
+package json_configs
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flagDevice struct {
+	Name    string        `json:"name"`
+	Host    string        `json:"host" usage:"Device address"`
+	Port    int           `json:"port"`
+	Enabled bool          `json:"enabled"`
+	Timeout time.Duration `json:"timeout"`
+	Limit   *int          `json:"limit"`
+	Secret  string        `json:"-"`
+}
+
+func TestBindFlags(t *testing.T) {
+	limit := 3
+	base := flagDevice{Name: "Fan", Host: "10.0.0.1", Port: 1}
+
+	tests := []struct {
+		name   string
+		args   []string
+		result flagDevice
+		err    string
+	}{
+		{
+			name:   "no flags",
+			result: base,
+		},
+		{
+			name:   "override",
+			args:   []string{"-host", "10.0.0.2", "-port", "2", "-timeout", "5s", "-limit", "3", "-enabled"},
+			result: flagDevice{Name: "Fan", Host: "10.0.0.2", Port: 2, Enabled: true, Timeout: 5 * time.Second, Limit: &limit},
+		},
+		{
+			name:   "bool with value",
+			args:   []string{"-enabled=false"},
+			result: base,
+		},
+		{
+			name: "bad value rejected in Set",
+			args: []string{"-port", "x"},
+			err:  `invalid value "x" for flag -port: integer x`,
+		},
+		{
+			name: "ignored field has no flag",
+			args: []string{"-Secret", "x"},
+			err:  "flag provided but not defined: -Secret",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			device := base
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			BindFlags(fs, &device)
+			if f := fs.Lookup("host"); f == nil || f.Usage != "Device address" {
+				t.Fatalf("expected -host with usage from tag, got %+v", f)
+			}
+
+			err := fs.Parse(test.args)
+			if err == nil {
+				err = ApplyFlags(fs, &device)
+			}
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(device, test.result) {
+				t.Errorf("got %+v, want %+v", device, test.result)
+			}
+		})
+	}
+}
+
+// Run ParseCommandLine with its own flag.CommandLine and os.Args
+func parseTestCommandLine(data interface{}, idName string, args ...string) (resultMap ResultMap, err error) {
+	defer func(commandLine *flag.FlagSet, osArgs []string) {
+		flag.CommandLine, os.Args = commandLine, osArgs
+	}(flag.CommandLine, os.Args)
+
+	flag.CommandLine = flag.NewFlagSet("config_device", flag.ContinueOnError)
+	flag.CommandLine.SetOutput(ioutil.Discard)
+	os.Args = append([]string{"config_device"}, args...)
+	return ParseCommandLine(data, idName)
+}
+
+func TestParseCommandLine(t *testing.T) {
+	dir := t.TempDir()
+	single := filepath.Join(dir, "single")
+	multi := filepath.Join(dir, "multi")
+	for filename, content := range map[string]string{
+		filepath.Join(single, "fan.json"): `{"name": "Fan", "port": 1}`,
+		filepath.Join(multi, "fan.json"):  `{"name": "Fan", "port": 1}`,
+		filepath.Join(multi, "lamp.yaml"): "name: Lamp\nport: 2\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		result ResultMap
+		err    string
+	}{
+		{
+			name: "directory only",
+			args: []string{"-c", multi},
+			result: ResultMap{
+				"Fan":  flagDevice{Name: "Fan", Port: 1},
+				"Lamp": flagDevice{Name: "Lamp", Port: 2},
+			},
+		},
+		{
+			name: "id flag selects the element",
+			args: []string{"-c", multi, "-name", "Lamp", "-port", "9"},
+			result: ResultMap{
+				"Fan":  flagDevice{Name: "Fan", Port: 1},
+				"Lamp": flagDevice{Name: "Lamp", Port: 9},
+			},
+		},
+		{
+			name:   "single element without id flag",
+			args:   []string{"-c", single, "-host", "10.0.0.9"},
+			result: ResultMap{"Fan": flagDevice{Name: "Fan", Host: "10.0.0.9", Port: 1}},
+		},
+		{
+			name: "ambiguous element",
+			args: []string{"-c", multi, "-port", "9"},
+			err:  "flags override a single element, select one of 2 with -name",
+		},
+		{
+			name: "no matching element",
+			args: []string{"-c", multi, "-name", "Pump", "-port", "9"},
+			err:  "flag -name Pump matches no element in " + multi,
+		},
+		{
+			name: "missing -c",
+			args: []string{"-port", "9"},
+			err:  "option -c <directory> required",
+		},
+		{
+			name: "-c not a directory",
+			args: []string{"-c", filepath.Join(single, "fan.json")},
+			err:  "is not a directory",
+		},
+		{
+			name: "-c missing",
+			args: []string{"-c", filepath.Join(dir, "missing")},
+			err:  "no such file or directory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var device flagDevice
+			resultMap, err := parseTestCommandLine(&device, "Name", test.args...)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				if resultMap != nil {
+					t.Errorf("expected nil resultMap for a command error, got %v", resultMap)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(resultMap, test.result) {
+				t.Errorf("got %+v, want %+v", resultMap, test.result)
+			}
+		})
+	}
+}
+
+func TestParseCommandLineClash(t *testing.T) {
+	var device struct {
+		Name   string `json:"name"`
+		Config string `json:"c"`
+	}
+	_, err := parseTestCommandLine(&device, "Name", "-c", t.TempDir())
+	if err == nil || err.Error() != "flag -c for parameter Config clashes with option -c <directory>" {
+		t.Errorf("expected -c clash, got %v", err)
+	}
+
+	// A flag defined by the program is not replaced
+	defer func(commandLine *flag.FlagSet) { flag.CommandLine = commandLine }(flag.CommandLine)
+	flag.CommandLine = flag.NewFlagSet("config_device", flag.ContinueOnError)
+	flag.CommandLine.String("port", "", "")
+	var flagged flagDevice
+	_, err = ParseCommandLine(&flagged, "Name")
+	if err == nil || err.Error() != "flag -port for parameter Port is already defined" {
+		t.Errorf("expected -port already defined, got %v", err)
+	}
+}
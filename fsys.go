@@ -0,0 +1,108 @@
+package json_configs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Config files are read through a fileSystem, either the OS filesystem or an io/fs.FS
+// - An fs.FS uses slash-separated paths, so full paths are rooted at "/", such as "/devices/fan.json"
+type fileSystem interface {
+	abs(name string) (string, error)
+	stat(name string) (fs.FileInfo, error)
+	readFile(name string) ([]byte, error)
+	open(name string) (io.ReadCloser, error)
+	glob(pattern string) ([]string, error)
+	walkDir(root string, fn fs.WalkDirFunc) error
+	isAbs(name string) bool
+	join(elem ...string) string
+	split(name string) (dir, file string)
+	dir(name string) string
+	base(name string) string
+}
+
+// The OS filesystem, with paths relative to the working directory
+type osFileSystem struct{}
+
+func (osFileSystem) abs(name string) (string, error)              { return filepath.Abs(name) }
+func (osFileSystem) stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFileSystem) readFile(name string) ([]byte, error)         { return ioutil.ReadFile(name) }
+func (osFileSystem) open(name string) (io.ReadCloser, error)      { return os.Open(name) }
+func (osFileSystem) glob(pattern string) ([]string, error)        { return filepath.Glob(pattern) }
+func (osFileSystem) walkDir(root string, fn fs.WalkDirFunc) error { return filepath.WalkDir(root, fn) }
+func (osFileSystem) isAbs(name string) bool                       { return filepath.IsAbs(name) }
+func (osFileSystem) join(elem ...string) string                   { return filepath.Join(elem...) }
+func (osFileSystem) split(name string) (dir, file string)         { return filepath.Split(name) }
+func (osFileSystem) dir(name string) string                       { return filepath.Dir(name) }
+func (osFileSystem) base(name string) string                      { return filepath.Base(name) }
+
+// An io/fs.FS, such as embed.FS or fstest.MapFS, with paths relative to its root
+type fsFileSystem struct {
+	fsys fs.FS
+}
+
+// Name within the FS, where a leading "/" is the FS root
+func (f fsFileSystem) name(name string) (fsName string, err error) {
+	fsName = path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(fsName) {
+		err = fmt.Errorf("invalid path %s", name)
+	}
+	return
+}
+
+func (f fsFileSystem) abs(name string) (fullpath string, err error) {
+	if name, err = f.name(name); err != nil {
+		return
+	}
+	if name == "." {
+		return "/", nil
+	}
+	return "/" + name, nil
+}
+
+func (f fsFileSystem) stat(name string) (info fs.FileInfo, err error) {
+	if name, err = f.name(name); err != nil {
+		return
+	}
+	return fs.Stat(f.fsys, name)
+}
+
+func (f fsFileSystem) readFile(name string) (b []byte, err error) {
+	if name, err = f.name(name); err != nil {
+		return
+	}
+	return fs.ReadFile(f.fsys, name)
+}
+
+func (f fsFileSystem) open(name string) (file io.ReadCloser, err error) {
+	if name, err = f.name(name); err != nil {
+		return
+	}
+	return f.fsys.Open(name)
+}
+
+func (f fsFileSystem) glob(pattern string) (matches []string, err error) {
+	if pattern, err = f.name(pattern); err != nil {
+		return
+	}
+	return fs.Glob(f.fsys, pattern)
+}
+
+func (f fsFileSystem) walkDir(root string, fn fs.WalkDirFunc) (err error) {
+	if root, err = f.name(root); err != nil {
+		return
+	}
+	return fs.WalkDir(f.fsys, root, fn)
+}
+
+func (f fsFileSystem) isAbs(name string) bool               { return strings.HasPrefix(name, "/") }
+func (f fsFileSystem) join(elem ...string) string           { return path.Join(elem...) }
+func (f fsFileSystem) split(name string) (dir, file string) { return path.Split(name) }
+func (f fsFileSystem) dir(name string) string               { return path.Dir(name) }
+func (f fsFileSystem) base(name string) string              { return path.Base(name) }
@@ -0,0 +1,165 @@
+package json_configs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type fsDevice struct {
+	Name  string `json:"name"`
+	Speed int    `json:"speed"`
+	Host  string `json:"host"`
+}
+
+func testMapFS() fstest.MapFS {
+	return fstest.MapFS{
+		"a/fan.json":        {Data: []byte(`{"name": "Fan", "speed": 1}`)},
+		"b/fan.json":        {Data: []byte(`{"name": "Lamp", "speed": "fast"}`)},
+		"c/devices.yaml":    {Data: []byte("- name: Fan\n  host: 10.0.0.1\n- name: Heater\n  speed: 2\n")},
+		"c/include.json":    {Data: []byte(`{"name": "Pump", "speed": 4, "$include": "../a/fan.json"}`)},
+		"c/notes.txt":       {Data: []byte("not a config")},
+		"d/cycle.json":      {Data: []byte(`{"name": "Cycle", "$include": "cycle.json"}`)},
+		"empty/.gitkeep":    {Data: []byte{}},
+		"top/settings.json": {Data: []byte(`{"name": "Top", "speed": 5}`)},
+	}
+}
+
+func TestDistinctFilenamesFS(t *testing.T) {
+	var errList []string
+
+	fileDetails := DistinctFilenamesFS(testMapFS(), []string{"b/fan.json", "a/fan.json", "/a/fan.json", "a/missing.json", "c"}, &errList)
+
+	var distinctNames, fullPaths []string
+	for _, file := range fileDetails {
+		distinctNames = append(distinctNames, file.DistinctName)
+		fullPaths = append(fullPaths, file.FullPath)
+	}
+	if want := []string{"b/fan.json", "a/fan.json"}; !reflect.DeepEqual(distinctNames, want) {
+		t.Errorf("distinct names: got %q, want %q", distinctNames, want)
+	}
+	if want := []string{"/b/fan.json", "/a/fan.json"}; !reflect.DeepEqual(fullPaths, want) {
+		t.Errorf("full paths: got %q, want %q", fullPaths, want)
+	}
+
+	// The duplicate, the missing file and the directory are each reported
+	errors := strings.Join(errList, "\n")
+	for _, want := range []string{"/a/fan.json", "no such file [a/missing.json]", "directory [c]"} {
+		if !strings.Contains(errors, want) {
+			t.Errorf("expected error containing %q, got %q", want, errList)
+		}
+	}
+	if len(errList) != 3 {
+		t.Errorf("expected 3 errors, got %q", errList)
+	}
+}
+
+func TestValidateFileFS(t *testing.T) {
+	tests := []struct {
+		file     string
+		fullpath string
+		err      string
+	}{
+		{"a/fan.json", "/a/fan.json", ""},
+		{"/a/fan.json", "/a/fan.json", ""},
+		{"a/../a/fan.json", "/a/fan.json", ""},
+		{"a/missing.json", "", "invalid, no such file [a/missing.json]"},
+		{"a", "", "invalid, filename is a directory [a]"},
+		{"../a/fan.json", "", "invalid path ../a/fan.json"},
+	}
+	for _, test := range tests {
+		fullpath, err := ValidateFileFS(testMapFS(), test.file)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", test.file, test.err, err)
+			}
+			continue
+		}
+		if err != nil || fullpath != test.fullpath {
+			t.Errorf("%s: got %q %v, want %q", test.file, fullpath, err, test.fullpath)
+		}
+	}
+}
+
+func TestReadConfigFilesFS(t *testing.T) {
+	var device fsDevice
+
+	resultMap, err := ReadConfigFilesFS(testMapFS(), &device, "Name", "a/fan.json", "b/fan.json", "c/devices.yaml")
+
+	// The bad setting in b/fan.json is reported by its distinct name, and the other files still read
+	if err == nil || !strings.Contains(err.Error(), "[b/fan.json]") {
+		t.Errorf("expected error naming b/fan.json, got %v", err)
+	}
+	want := ResultMap{
+		"Fan":    fsDevice{Name: "Fan", Speed: 1, Host: "10.0.0.1"},
+		"Heater": fsDevice{Name: "Heater", Speed: 2},
+	}
+	for name, v := range want {
+		if !reflect.DeepEqual(resultMap[name], v) {
+			t.Errorf("%s: got %+v, want %+v", name, resultMap[name], v)
+		}
+	}
+}
+
+func TestReadConfigFilesFSInclude(t *testing.T) {
+	var device fsDevice
+
+	resultMap, err := ReadConfigFilesFS(testMapFS(), &device, "Name", "c/include.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resultMap) != 2 || resultMap["Fan"].(fsDevice).Speed != 1 || resultMap["Pump"].(fsDevice).Speed != 4 {
+		t.Errorf("expected Pump and included Fan, got %+v", resultMap)
+	}
+
+	_, err = ReadConfigFilesFS(testMapFS(), &device, "Name", "d/cycle.json")
+	if err == nil || !strings.Contains(err.Error(), "d/cycle.json") {
+		t.Errorf("expected include error naming d/cycle.json, got %v", err)
+	}
+}
+
+func TestReadConfigFileFS(t *testing.T) {
+	var device fsDevice
+
+	if err := ReadConfigFileFS(testMapFS(), &device, "top/settings.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (fsDevice{Name: "Top", Speed: 5}); device != want {
+		t.Errorf("got %+v, want %+v", device, want)
+	}
+	if err := ReadConfigFileFS(testMapFS(), &device, "top/missing.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestReadConfigDirFS(t *testing.T) {
+	var device fsDevice
+
+	tests := []struct {
+		opts  DirOptions
+		names []string
+	}{
+		{DirOptions{}, []string{"Fan", "Heater", "Pump"}},
+		{DirOptions{Include: []string{"*.json"}}, []string{"Fan", "Pump"}},
+	}
+	for _, test := range tests {
+		resultMap, err := ReadConfigDirFS(testMapFS(), &device, "Name", "c", test.opts)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.opts.Include, err)
+		}
+		names := make([]string, 0, len(resultMap))
+		for name := range resultMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%v: got %q, want %q", test.opts.Include, names, test.names)
+		}
+	}
+
+	if _, err := ReadConfigDirFS(testMapFS(), &device, "Name", "empty", DirOptions{}); err == nil {
+		t.Errorf("expected error for directory without config files")
+	}
+}
@@ -0,0 +1,206 @@
+package json_configs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options for fetching a config document by URL
+// - Timeout limits each request, DefaultHTTPTimeout if not set
+// - CacheDir keeps the last copy of each document, revalidated with ETag and If-Modified-Since,
+// and used if the server can't be reached
+// - Client is used for requests if set, such as the Client() of an httptest.Server
+type HTTPOptions struct {
+	Timeout  time.Duration
+	CacheDir string
+	Client   *http.Client
+}
+
+const DefaultHTTPTimeout = 30 * time.Second
+
+// A config document fetched by URL, for ReadConfigSources()
+// - The URL is the distinct name, so messages name the URL, such as [https://config.local/devices.json]
+// - The document is fetched when the source is read, and a fetch error is reported as a read error
+// - Include directives in the document are reported as errors, and never resolved in the local filesystem
+func HTTPSource(url string, opts HTTPOptions) Source {
+	return Source{Name: url, Reader: &httpReader{url: url, opts: opts}}
+}
+
+// Check if a source name is a URL
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// Reader that fetches a URL on first read
+type httpReader struct {
+	url     string
+	opts    HTTPOptions
+	r       io.Reader
+	err     error
+	fetched bool
+}
+
+func (h *httpReader) Read(p []byte) (n int, err error) {
+	var b []byte
+
+	if !h.fetched {
+		h.fetched = true
+		b, h.err = fetchHTTP(h.url, h.opts)
+		h.r = bytes.NewReader(b)
+	}
+	if h.err != nil {
+		return 0, h.err
+	}
+	return h.r.Read(p)
+}
+
+// Validators for a cached document, kept next to it in CacheDir
+type httpCacheMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Fetch a URL, revalidating any cached copy, and falling back to it if the server can't be reached
+func fetchHTTP(url string, opts HTTPOptions) (b []byte, err error) {
+	var req *http.Request
+	var resp *http.Response
+	var meta httpCacheMeta
+	var cached []byte
+	var cachePath string
+	var hasCache bool
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err != nil {
+		return
+	}
+
+	// A cached copy is revalidated, so an unchanged document isn't sent again
+	if len(opts.CacheDir) > 0 {
+		sum := sha256.Sum256([]byte(url))
+		cachePath = filepath.Join(opts.CacheDir, hex.EncodeToString(sum[:]))
+		cached, meta, hasCache = readHTTPCache(cachePath, url)
+		if hasCache && len(meta.ETag) > 0 {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if hasCache && len(meta.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	if resp, err = client.Do(req); err != nil {
+		if hasCache {
+			warn(fmt.Sprintf("fetching config failed, %v, using cached copy [%s]", err, url))
+			return cached, nil
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCache:
+		return cached, nil
+	case resp.StatusCode == http.StatusOK:
+		if b, err = ioutil.ReadAll(resp.Body); err != nil {
+			if hasCache {
+				warn(fmt.Sprintf("fetching config failed, %v, using cached copy [%s]", err, url))
+				return cached, nil
+			}
+			return
+		}
+	case resp.StatusCode >= 500 && hasCache:
+		warn(fmt.Sprintf("fetching config failed, HTTP %s, using cached copy [%s]", resp.Status, url))
+		return cached, nil
+	default:
+		err = fmt.Errorf("HTTP %s", resp.Status)
+		return
+	}
+
+	if len(cachePath) > 0 {
+		meta = httpCacheMeta{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+		if cacheErr := writeHTTPCache(cachePath, meta, b); cacheErr != nil {
+			warn(fmt.Sprintf("caching config failed, %v [%s]", cacheErr, url))
+		}
+	}
+	return
+}
+
+// Read a cached document and its validators, if both are present and for the same URL
+func readHTTPCache(cachePath, url string) (b []byte, meta httpCacheMeta, ok bool) {
+	var m []byte
+	var err error
+
+	if m, err = ioutil.ReadFile(cachePath + ".meta"); err != nil {
+		return
+	}
+	if err = json.Unmarshal(m, &meta); err != nil || meta.URL != url {
+		return
+	}
+	if b, err = ioutil.ReadFile(cachePath); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// Write a cached document, then its validators, each replaced as a whole
+func writeHTTPCache(cachePath string, meta httpCacheMeta, b []byte) (err error) {
+	var m []byte
+
+	if err = os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return
+	}
+	if m, err = json.Marshal(meta); err != nil {
+		return
+	}
+	// Validators are removed first, so they never describe a different copy
+	if err = os.Remove(cachePath + ".meta"); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = writeFileAtomic(cachePath, b); err != nil {
+		return
+	}
+	return writeFileAtomic(cachePath+".meta", m)
+}
+
+func writeFileAtomic(filename string, b []byte) (err error) {
+	var f *os.File
+
+	if f, err = ioutil.TempFile(filepath.Dir(filename), ".tmp-"); err != nil {
+		return
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err = os.Rename(f.Name(), filename); err != nil {
+		os.Remove(f.Name())
+	}
+	return
+}
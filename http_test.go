@@ -0,0 +1,112 @@
+package json_configs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type httpDevice struct {
+	Name  string `json:"name"`
+	Speed int    `json:"speed"`
+}
+
+func TestHTTPSourceCache(t *testing.T) {
+	var warnings []string
+	var requests, notModified int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/devices.json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[{"name": "Fan", "speed": 3}, {"name": "Lamp", "speed": "fast"}]`))
+	}))
+	url := server.URL + "/devices.json"
+
+	defer func(handler func(string)) { WarningHandler = handler }(WarningHandler)
+	WarningHandler = func(warning string) { warnings = append(warnings, warning) }
+
+	opts := HTTPOptions{CacheDir: t.TempDir(), Client: server.Client()}
+	for i := 0; i < 2; i++ {
+		var device httpDevice
+		resultMap, err := ReadConfigSources(&device, "Name", HTTPSource(url, opts))
+		if err == nil || !strings.Contains(err.Error(), "["+url+":elem#2]") {
+			t.Errorf("read %d: expected error naming %s, got %v", i+1, url, err)
+		}
+		if resultMap["Fan"].(httpDevice).Speed != 3 {
+			t.Errorf("read %d: expected Fan speed 3, got %+v", i+1, resultMap["Fan"])
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("expected 2 requests, 1 not modified, got %d and %d", requests, notModified)
+	}
+
+	// Offline, the cached copy is used with a warning
+	server.Close()
+	var device httpDevice
+	resultMap, _ := ReadConfigSources(&device, "Name", HTTPSource(url, opts))
+	if _, ok := resultMap["Fan"]; !ok {
+		t.Errorf("expected Fan from cached copy, got %v", resultMap)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "using cached copy ["+url+"]") {
+		t.Errorf("expected warning for cached copy, got %q", warnings)
+	}
+
+	// Without a cache, the fetch error names the URL
+	_, err := ReadConfigSources(&device, "Name", HTTPSource(url, HTTPOptions{Client: server.Client()}))
+	if err == nil || !strings.Contains(err.Error(), url) {
+		t.Errorf("expected fetch error naming %s, got %v", url, err)
+	}
+}
+
+func TestHTTPSourceStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	var device httpDevice
+	url := server.URL + "/missing.json"
+	_, err := ReadConfigSources(&device, "Name", HTTPSource(url, HTTPOptions{Client: server.Client()}))
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), url) {
+		t.Errorf("expected 404 error naming %s, got %v", url, err)
+	}
+}
+
+func TestHTTPSourceInclude(t *testing.T) {
+	// A local file that a fetched document must not be able to include
+	dir := t.TempDir()
+	local := filepath.Join(dir, "secret.json")
+	if err := ioutil.WriteFile(local, []byte(`{"name": "Fan", "leak": "secret"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, include := range []string{filepath.Join(dir, "*.json"), "secret.json", "../" + filepath.Base(dir) + "/secret.json"} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name": "Fan", "speed": 1, "$include": "` + include + `"}`))
+		}))
+		url := server.URL + "/fan.json"
+
+		var device httpDevice
+		resultMap, err := ReadConfigSources(&device, "Name", HTTPSource(url, HTTPOptions{Client: server.Client()}))
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), "can't include files ["+url+"]") {
+			t.Errorf("include %s: expected include error, got %v", include, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "leak") {
+			t.Errorf("include %s: local file was read, %v", include, err)
+		}
+		if resultMap["Fan"].(httpDevice).Speed != 1 {
+			t.Errorf("include %s: expected Fan speed 1, got %+v", include, resultMap["Fan"])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package json_configs
+
+import (
+	"fmt"
+)
+
+// A config file can pull in other files with an IncludeKey, at the top level or as an array entry
+// - For example, {"$include": ["common/*.json"]}
+// - Patterns are relative to the including file, and may be globs
+const IncludeKey = "$include"
+
+// Read each listed file, then the files it includes, in one pass, checking for include cycles
+// - read is called once for each file, returning the include patterns found as the file is decoded,
+// or ok false if the file failed to read, so its includes aren't followed
+// - Included files are read right after the including file, in the same layer
+// - An archive is expanded to its members, each in the archive's layer
+// - Returns the files read with their distinct names, and includeMap of the chain of including files,
+// by full path of each included file
+func readIncludes(fsys fileSystem, filenames []string, layerMap map[string]int, errList *[]string,
+	read func(file FileDetail) (patterns []string, ok bool)) (fileDetails []FileDetail, includeMap map[string][]string) {
+	var filename string
+
+	includeMap = make(map[string][]string)
+	seen := make(map[string]int)
+
+	var include func(filename string, chain []string)
+	include = func(filename string, chain []string) {
+		var file FileDetail
+		var patterns, matches []string
+		var pattern, match, matchpath, ancestor string
+		var err error
+		var i int
+		var ok, cycle bool
+
+		// An archive is expanded to its members
+		if archive, isArchiveFS := fsys.(*archiveFileSystem); isArchiveFS && isArchive(filename) {
+			if matches, err = archive.members(filename); err != nil {
+				*errList = append(*errList, err.Error())
+				return
+			}
+			if len(matches) == 0 {
+				*errList = append(*errList, fmt.Sprintf("no config files found in archive [%s]", filename))
+				return
+			}
+			fullpath, _ := fsys.abs(filename)
+			for _, match = range matches {
+				if matchpath, err = fsys.abs(match); err != nil {
+					*errList = append(*errList, fmt.Sprintf("%v [%s]", err, match))
+					continue
+				}
+				if _, ok = includeMap[matchpath]; !ok && len(chain) > 0 {
+					includeMap[matchpath] = chain
+				}
+				if _, ok = layerMap[matchpath]; !ok {
+					layerMap[matchpath] = layerMap[fullpath]
+				}
+				include(match, chain)
+			}
+			return
+		}
+
+		// Make sure each file is valid, and read only once
+		if file, err = newFileDetail(fsys, filename); err != nil {
+			*errList = append(*errList, err.Error())
+			return
+		}
+		if i, ok = seen[file.FullPath]; ok {
+			*errList = append(*errList, fmt.Sprintf("file is duplicate of %s, skipping [%s]", fileDetails[i].Name, filename))
+			return
+		}
+		seen[file.FullPath] = len(fileDetails)
+		fileDetails = append(fileDetails, file)
+
+		if patterns, ok = read(file); !ok {
+			return
+		}
+		chain = append(chain[:len(chain):len(chain)], file.FullPath)
+
+		for _, pattern = range patterns {
+			// A document fetched by URL could otherwise include local files
+			if isURL(filename) {
+				*errList = append(*errList, fmt.Sprintf("include %s invalid, a document fetched by URL can't include files [%s]",
+					pattern, filename))
+				continue
+			}
+			if !fsys.isAbs(pattern) {
+				pattern = fsys.join(fsys.dir(filename), pattern)
+			}
+			matches, err = fsys.glob(pattern)
+			if err != nil {
+				*errList = append(*errList, fmt.Sprintf("include %s invalid, %v [%s]", pattern, err, filename))
+				continue
+			}
+			if len(matches) == 0 {
+				*errList = append(*errList, fmt.Sprintf("include %s matches no files [%s]", pattern, filename))
+				continue
+			}
+
+			for _, match = range matches {
+				matchpath, err = fsys.abs(match)
+				if err != nil {
+					*errList = append(*errList, fmt.Sprintf("%v [%s]", err, match))
+					continue
+				}
+
+				// An include of a file in its own chain is a cycle
+				cycle = false
+				for _, ancestor = range chain {
+					if ancestor == matchpath {
+						cycle = true
+						break
+					}
+				}
+				if cycle {
+					*errList = append(*errList, fmt.Sprintf("include cycle, %s is in its own include chain, skipping [%s]",
+						match, filename))
+					continue
+				}
+
+				if _, ok = includeMap[matchpath]; !ok {
+					includeMap[matchpath] = chain
+				}
+				if _, ok = layerMap[matchpath]; !ok {
+					layerMap[matchpath] = layerMap[file.FullPath]
+				}
+				include(match, chain)
+			}
+		}
+	}
+
+	for _, filename = range filenames {
+		include(filename, nil)
+	}
+	fileDetails = distinctNames(fsys, fileDetails)
+	return
+}
+
+// List include patterns in a parsed config, from a single element or array entries
+func includePatterns(config interface{}) (patterns []string, err error) {
+	var elements []interface{}
+	var element, v, item interface{}
+	var ok bool
+
+	switch c := config.(type) {
+	case map[string]interface{}:
+		elements = []interface{}{c}
+	case []interface{}:
+		elements = c
+	}
+
+	for _, element = range elements {
+		elementMap, isMap := element.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		v, ok = elementMap[IncludeKey]
+		if !ok {
+			continue
+		}
+		switch include := v.(type) {
+		case string:
+			patterns = append(patterns, include)
+		case []interface{}:
+			for _, item = range include {
+				pattern, isString := item.(string)
+				if !isString {
+					err = fmt.Errorf("%s must list strings, not %v", IncludeKey, item)
+					return
+				}
+				patterns = append(patterns, pattern)
+			}
+		default:
+			err = fmt.Errorf("%s must be a string or list of strings, not %v", IncludeKey, v)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,151 @@
+package json_configs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resolve inheritance for elements with an ExtendsKey, after grouping by element Id
+// - Chains are supported, so Fan3 can extend Fan2, which extends Fan
+// - Elements with a missing parent or an inheritance cycle keep only their own settings
+func resolveExtends(parsedMap ParsedMap, idName, idTag string, errList *[]string) {
+	var elementId, parentId, location string
+	var parsedArr []Parsed
+	var v interface{}
+	var ok bool
+
+	// Find the parent of each element, and where it was specified
+	parentMap := make(map[string]string)
+	locationMap := make(map[string]string)
+	for elementId, parsedArr = range parsedMap {
+		for _, parsed := range parsedArr {
+			v, ok = parsed.ElementMap[ExtendsKey]
+			if !ok || v == nil {
+				continue
+			}
+			parentId = fmt.Sprintf("%v", v)
+			location, ok = locationMap[elementId]
+			if ok && parentMap[elementId] != parentId {
+				*errList = append(*errList, fmt.Sprintf("settings for %s conflict, %s: %q [%s] != %q [%s]",
+					elementId, ExtendsKey, parentMap[elementId], location, parentId, parsed.Location()))
+				continue
+			}
+			parentMap[elementId] = parentId
+			locationMap[elementId] = parsed.Location()
+		}
+	}
+
+	// Walk the element Ids in order, so each cycle is found and reported once, from the same element
+	elementIds := make([]string, 0, len(parentMap))
+	for elementId = range parentMap {
+		elementIds = append(elementIds, elementId)
+	}
+	sort.Strings(elementIds)
+	cycleMap := make(map[string]bool)
+	walkedMap := make(map[string]bool)
+	for _, elementId = range elementIds {
+		var chain []string
+		pathMap := make(map[string]int)
+		for id := elementId; !walkedMap[id]; id = parentMap[id] {
+			if start, onPath := pathMap[id]; onPath {
+				for _, member := range chain[start:] {
+					cycleMap[member] = true
+				}
+				*errList = append(*errList, fmt.Sprintf("inheritance cycle for %s, %s -> %s [%s]",
+					id, strings.Join(chain[start:], " -> "), id, locationMap[id]))
+				break
+			}
+			if _, ok = parentMap[id]; !ok {
+				break
+			}
+			pathMap[id] = len(chain)
+			chain = append(chain, id)
+		}
+		for _, id := range chain {
+			walkedMap[id] = true
+		}
+	}
+
+	// Resolve parents first, so settings are inherited down the chain
+	// - Members of a cycle keep only their own settings, while elements extending them inherit those
+	resolvedMap := make(ParsedMap)
+	var resolve func(elementId string, chain []string) []Parsed
+	resolve = func(elementId string, chain []string) (parsedArr []Parsed) {
+		var parentArr []Parsed
+		var parentId string
+		var ok bool
+
+		parsedArr, ok = resolvedMap[elementId]
+		if ok {
+			return
+		}
+		parsedArr = parsedMap[elementId]
+		resolvedMap[elementId] = parsedArr
+
+		parentId, ok = parentMap[elementId]
+		if !ok || cycleMap[elementId] {
+			return
+		}
+		chain = append(chain, elementId)
+		if len(chain) > MAX_ITERATIONS {
+			*errList = append(*errList, fmt.Sprintf("inheritance chain for %s exceeds %d elements [%s]",
+				elementId, MAX_ITERATIONS, locationMap[elementId]))
+			return
+		}
+		if _, ok = parsedMap[parentId]; !ok {
+			*errList = append(*errList, fmt.Sprintf("setting for %s invalid, %s: element %s not found [%s]",
+				elementId, ExtendsKey, parentId, locationMap[elementId]))
+			return
+		}
+
+		parentArr = resolve(parentId, chain)
+		parsedArr = append(inheritParsed(parentArr, parentId, idName, idTag), parsedArr...)
+		resolvedMap[elementId] = parsedArr
+		return
+	}
+
+	for _, elementId = range elementIds {
+		resolve(elementId, nil)
+	}
+	for elementId, parsedArr = range resolvedMap {
+		parsedMap[elementId] = parsedArr
+	}
+}
+
+// Settings for the wildcard Id apply to every other element, unless the element sets them itself
+func inheritWildcard(parsedMap ParsedMap, idName, idTag string) {
+	var elementId string
+
+	parsedArr, ok := parsedMap[WildcardId]
+	if !ok {
+		return
+	}
+	delete(parsedMap, WildcardId)
+	for elementId = range parsedMap {
+		parsedMap[elementId] = append(inheritParsed(parsedArr, WildcardId, idName, idTag),
+			parsedMap[elementId]...)
+	}
+}
+
+// Copy settings from parsedArr to inherit into another element, leaving out the element Id
+// - Inherited settings come first, so the element's own settings override them
+func inheritParsed(parsedArr []Parsed, inheritedFrom, idName, idTag string) (inherited []Parsed) {
+	var k string
+	var v interface{}
+
+	for _, parsed := range parsedArr {
+		elementMap := make(ElementMap)
+		for k, v = range parsed.ElementMap {
+			if k != idName && k != idTag && k != ExtendsKey {
+				elementMap[k] = v
+			}
+		}
+		parsed.ElementMap = elementMap
+		if len(parsed.InheritedFrom) == 0 {
+			parsed.InheritedFrom = inheritedFrom
+		}
+		inherited = append(inherited, parsed)
+	}
+	return
+}
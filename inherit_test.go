@@ -0,0 +1,88 @@
+package json_configs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type inheritDevice struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func TestResolveExtends(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		result ResultMap
+		errs   []string
+	}{
+		{
+			name: "chain",
+			config: `[{"name": "Fan", "host": "a", "port": 1}, {"name": "Fan2", "extends": "Fan", "port": 2},
+				{"name": "Fan3", "extends": "Fan2", "host": "c"}]`,
+			result: ResultMap{
+				"Fan":  inheritDevice{Name: "Fan", Host: "a", Port: 1},
+				"Fan2": inheritDevice{Name: "Fan2", Host: "a", Port: 2},
+				"Fan3": inheritDevice{Name: "Fan3", Host: "c", Port: 2},
+			},
+		},
+		{
+			name:   "missing parent",
+			config: `[{"name": "Fan", "extends": "Lamp", "port": 1}]`,
+			result: ResultMap{"Fan": inheritDevice{Name: "Fan", Port: 1}},
+			errs:   []string{"setting for Fan invalid, extends: element Lamp not found [d.json:elem#1]"},
+		},
+		{
+			name:   "cycle",
+			config: `[{"name": "B", "extends": "A", "host": "b"}, {"name": "A", "extends": "B", "port": 1}]`,
+			result: ResultMap{
+				"A": inheritDevice{Name: "A", Port: 1},
+				"B": inheritDevice{Name: "B", Host: "b"},
+			},
+			errs: []string{"inheritance cycle for A, A -> B -> A [d.json:elem#2]"},
+		},
+		{
+			name:   "self",
+			config: `[{"name": "A", "extends": "A", "port": 1}]`,
+			result: ResultMap{"A": inheritDevice{Name: "A", Port: 1}},
+			errs:   []string{"inheritance cycle for A, A -> A [d.json:elem#1]"},
+		},
+		{
+			name: "extending a cycle",
+			config: `[{"name": "A", "extends": "B", "port": 1}, {"name": "B", "extends": "C", "host": "b"},
+				{"name": "C", "extends": "B", "port": 3}, {"name": "D", "extends": "C"}]`,
+			result: ResultMap{
+				"A": inheritDevice{Name: "A", Host: "b", Port: 1},
+				"B": inheritDevice{Name: "B", Host: "b"},
+				"C": inheritDevice{Name: "C", Port: 3},
+				"D": inheritDevice{Name: "D", Port: 3},
+			},
+			errs: []string{"inheritance cycle for B, B -> C -> B [d.json:elem#2]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Repeat, as the outcome must not depend on map order
+			for i := 0; i < 20; i++ {
+				var device inheritDevice
+				fsys := fstest.MapFS{"d.json": {Data: []byte(test.config)}}
+				resultMap, err := ReadConfigFilesFS(fsys, &device, "Name", "d.json")
+				if !reflect.DeepEqual(resultMap, test.result) {
+					t.Fatalf("got %+v, want %+v", resultMap, test.result)
+				}
+				var errs []string
+				if err != nil {
+					errs = strings.Split(err.Error(), "\n")
+				}
+				if !reflect.DeepEqual(errs, test.errs) {
+					t.Fatalf("errors:\n got  %q\n want %q", errs, test.errs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package json_configs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// INIDecoder parses INI config files, of "key = value" or "key: value" lines, with ; or # comments
+// - A file without sections is a single element
+// - Each [section] is an element, with the section name as element Id unless the section sets it
+// - Keys before the first section are an element of their own, such as the WildcardId
+// - Values are strings, with enclosing quotes removed, and convert to each field's type, even with StrictTypes
+type INIDecoder struct{}
+
+func (INIDecoder) Decode(b []byte) (doc Document, err error) {
+	var elements []interface{}
+	var element map[string]interface{}
+	var key, value, text string
+	var position, i int
+
+	doc.Untyped = true
+	text = strings.Replace(strings.TrimPrefix(string(b), "\ufeff"), "\r\n", "\n", -1)
+	element = make(map[string]interface{})
+	for i, text = range strings.Split(text, "\n") {
+		text = strings.TrimSpace(text)
+		if len(text) == 0 || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+
+		// A section starts the next element
+		if text[0] == '[' {
+			if !strings.HasSuffix(text, "]") || len(text) < 3 {
+				err = fmt.Errorf("ini: line %d: invalid section %s", i+1, text)
+				return
+			}
+			if len(element) > 0 || position > 0 {
+				elements = append(elements, element)
+			}
+			if position == 0 && len(element) > 0 {
+				// Keys before the first section are the first element
+				doc.Lines[1] = doc.Lines[0]
+				delete(doc.Lines, 0)
+			}
+			position = len(elements) + 1
+			element = make(map[string]interface{})
+			if doc.Ids == nil {
+				doc.Ids = make(map[int]string)
+			}
+			doc.Ids[position] = strings.TrimSpace(text[1 : len(text)-1])
+			continue
+		}
+
+		n := strings.IndexAny(text, "=:")
+		if n <= 0 {
+			err = fmt.Errorf("ini: line %d: expected key = value, found %s", i+1, text)
+			return
+		}
+		key = strings.TrimSpace(text[:n])
+		value = strings.TrimSpace(text[n+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, ok := element[key]; ok {
+			err = fmt.Errorf("ini: line %d: duplicate key %s", i+1, key)
+			return
+		}
+		element[key] = value
+		doc.setLine(position, key, i+1)
+	}
+
+	if doc.Ids == nil {
+		doc.Config = element
+		return
+	}
+	elements = append(elements, element)
+	doc.Config = elements
+	return
+}
@@ -0,0 +1,68 @@
+package json_configs
+
+import (
+	"testing"
+)
+
+func TestINIDecoder(t *testing.T) {
+	runDecoderTests(t, INIDecoder{}, []decoderTest{
+		{
+			name:   "no sections",
+			input:  "; device\nname = Fan\nport: 21000\n# comment\nhost = \"10.0.0.1\"\nmotd = 'a = b'\n",
+			config: map[string]interface{}{"name": "Fan", "port": "21000", "host": "10.0.0.1", "motd": "a = b"},
+			lines:  map[int]map[string]int{0: {"name": 2, "port": 3, "host": 5, "motd": 6}},
+		},
+		{
+			name:  "sections",
+			input: "[Fan]\nport = 1\n\n[ Lamp ]\nport = 2\nname = Desk Lamp\n",
+			config: []interface{}{
+				map[string]interface{}{"port": "1"},
+				map[string]interface{}{"port": "2", "name": "Desk Lamp"},
+			},
+			lines: map[int]map[string]int{1: {"port": 2}, 2: {"port": 5, "name": 6}},
+			ids:   map[int]string{1: "Fan", 2: "Lamp"},
+		},
+		{
+			name:  "keys before first section",
+			input: "timeout = 30\n[Fan]\nport = 1\n",
+			config: []interface{}{
+				map[string]interface{}{"timeout": "30"},
+				map[string]interface{}{"port": "1"},
+			},
+			lines: map[int]map[string]int{1: {"timeout": 1}, 2: {"port": 3}},
+			ids:   map[int]string{2: "Fan"},
+		},
+		{
+			name:  "empty section",
+			input: "[Fan]\n[Lamp]\nport = 2\n",
+			config: []interface{}{
+				map[string]interface{}{},
+				map[string]interface{}{"port": "2"},
+			},
+			ids: map[int]string{1: "Fan", 2: "Lamp"},
+		},
+		{
+			name:  "same key in two sections",
+			input: "[Fan]\nport = 1\n[Lamp]\nport = 2\n",
+			config: []interface{}{
+				map[string]interface{}{"port": "1"},
+				map[string]interface{}{"port": "2"},
+			},
+		},
+		{
+			name:  "duplicate key",
+			input: "[Fan]\nport = 1\nport = 2\n",
+			err:   "ini: line 3: duplicate key port",
+		},
+		{
+			name:  "missing value",
+			input: "[Fan]\nport\n",
+			err:   "ini: line 2: expected key = value, found port",
+		},
+		{
+			name:  "invalid section",
+			input: "[Fan\nport = 1\n",
+			err:   "ini: line 1: invalid section [Fan",
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package json_configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffix of each source from a key-per-file directory
+const KeyPerFileSuffix = ".keys.json"
+
+// Read a key-per-file directory, such as a mounted Kubernetes ConfigMap or Docker secrets, as sources for ReadConfigSources()
+// - Each subdirectory <dir>/<elementID> is an element, and each file in it a setting, such as secrets/fan/password
+// - The file content is the value, with trailing newlines trimmed, and converts to the field's type
+// - idKey is the config key for the element id, such as "name", set to the subdirectory name unless a file sets it
+// - Entries starting with "..", such as the ..data symlink of a ConfigMap, are skipped, while the symlinks to it are followed
+// - Files directly in 'dir' are not settings of any element, and are skipped
+// - Each source is named <dir>/<elementID>.keys.json, so it is always decoded as JSON, and never as an overlay
+// - Values are strings, converted to each field's type, even with StrictTypes
+func KeyPerFileSources(dir string, idKey string) (sources []Source, err error) {
+	var entries, fieldEntries []os.DirEntry
+	var elementMap map[string]interface{}
+	var info os.FileInfo
+	var b []byte
+	var elementDir, filename string
+
+	if entries, err = os.ReadDir(dir); err != nil {
+		err = fmt.Errorf("reading directory: %v [%s]", err, dir)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		if isKeyPerFileMeta(entry.Name()) {
+			continue
+		}
+		elementDir = filepath.Join(dir, entry.Name())
+
+		// Stat follows symlinks, so a linked element directory is read like any other
+		if info, err = os.Stat(elementDir); err != nil {
+			err = fmt.Errorf("reading directory: %v [%s]", err, dir)
+			return
+		}
+		if !info.IsDir() {
+			continue
+		}
+
+		if fieldEntries, err = os.ReadDir(elementDir); err != nil {
+			err = fmt.Errorf("reading directory: %v [%s]", err, elementDir)
+			return
+		}
+		elementMap = make(map[string]interface{})
+		for _, fieldEntry := range fieldEntries {
+			if isKeyPerFileMeta(fieldEntry.Name()) {
+				continue
+			}
+			filename = filepath.Join(elementDir, fieldEntry.Name())
+			if info, err = os.Stat(filename); err != nil {
+				err = fmt.Errorf("reading file: %v", err)
+				return
+			}
+			if info.IsDir() {
+				continue
+			}
+			if b, err = ioutil.ReadFile(filename); err != nil {
+				err = fmt.Errorf("reading file: %v", err)
+				return
+			}
+			elementMap[fieldEntry.Name()] = strings.TrimRight(string(b), "\r\n")
+		}
+		if len(elementMap) == 0 {
+			continue
+		}
+		if _, ok := elementMap[idKey]; !ok && len(idKey) > 0 {
+			elementMap[idKey] = entry.Name()
+		}
+
+		// Each element is a JSON source, named by its directory with an extension to pick the decoder
+		if b, err = json.Marshal(elementMap); err != nil {
+			err = fmt.Errorf("encoding element: %v [%s]", err, elementDir)
+			return
+		}
+		sources = append(sources, Source{Name: elementDir + KeyPerFileSuffix, Reader: bytes.NewReader(b)})
+	}
+	if len(sources) == 0 {
+		err = fmt.Errorf("no config found in directory [%s]", dir)
+	}
+	return
+}
+
+// Decoder for key-per-file sources, JSON objects of strings
+type keyPerFileDecoder struct{}
+
+func (keyPerFileDecoder) Decode(b []byte) (doc Document, err error) {
+	if doc, err = (JSONDecoder{}).Decode(b); err == nil {
+		doc.Untyped = true
+	}
+	return
+}
+
+// Check if an entry is metadata of a mounted volume, such as ..data or a timestamped ..2024_01_01 directory
+// - Other names are keys, even hidden or backup names such as .env or key~
+func isKeyPerFileMeta(name string) bool {
+	return strings.HasPrefix(name, "..")
+}
@@ -0,0 +1,116 @@
+package json_configs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type keyPerFileDevice struct {
+	Name    string `json:"name"`
+	Port    int    `json:"port"`
+	Enabled bool   `json:"enabled"`
+	Env     string `json:".env"`
+}
+
+// Make a symlink, skipping the test where symlinks aren't supported
+func symlinkTest(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+}
+
+func TestKeyPerFileSources(t *testing.T) {
+	// A ConfigMap volume, where each element directory links through ..data to a timestamped directory
+	configMap := t.TempDir()
+	writeTestFiles(t, configMap, map[string]string{
+		"..2024_01_01_00_00_00.1/Fan/port":    "1\n",
+		"..2024_01_01_00_00_00.1/Fan/enabled": "true",
+		"..2024_01_01_00_00_00.1/Lamp/port":   "2\r\n",
+		"..2024_01_01_00_00_00.1/Lamp/name":   "Desk Lamp\n",
+	})
+	symlinkTest(t, "..2024_01_01_00_00_00.1", filepath.Join(configMap, "..data"))
+	symlinkTest(t, filepath.Join("..data", "Fan"), filepath.Join(configMap, "Fan"))
+	symlinkTest(t, filepath.Join("..data", "Lamp"), filepath.Join(configMap, "Lamp"))
+
+	// Secrets mounted per element, where each key links through the element's own ..data
+	secrets := t.TempDir()
+	writeTestFiles(t, secrets, map[string]string{
+		"Fan/..2024_01_01_00_00_00.1/port": "1",
+		"Fan/.env":                         "prod",
+		"Fan/port~":                        "9",
+		"Fan/sub/port":                     "9",
+		"notes.txt":                        "not an element",
+		"Empty/..data/port":                "9",
+	})
+	symlinkTest(t, "..2024_01_01_00_00_00.1", filepath.Join(secrets, "Fan", "..data"))
+	symlinkTest(t, filepath.Join("..data", "port"), filepath.Join(secrets, "Fan", "port"))
+
+	tests := []struct {
+		name    string
+		dir     string
+		sources []string
+		result  ResultMap
+		err     string
+	}{
+		{
+			name:    "ConfigMap",
+			dir:     configMap,
+			sources: []string{"Fan.keys.json", "Lamp.keys.json"},
+			result: ResultMap{
+				"Fan":       keyPerFileDevice{Name: "Fan", Port: 1, Enabled: true},
+				"Desk Lamp": keyPerFileDevice{Name: "Desk Lamp", Port: 2},
+			},
+		},
+		{
+			name:    "secrets",
+			dir:     secrets,
+			sources: []string{"Fan.keys.json"},
+			result:  ResultMap{"Fan": keyPerFileDevice{Name: "Fan", Port: 1, Env: "prod"}},
+			err:     `unused setting for Fan, parameter port~`,
+		},
+		{
+			name: "empty",
+			dir:  t.TempDir(),
+			err:  "no config found in directory",
+		},
+		{
+			name: "missing",
+			dir:  filepath.Join(secrets, "missing"),
+			err:  "reading directory:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sources, err := KeyPerFileSources(test.dir, "name")
+			if err == nil {
+				var names []string
+				for _, source := range sources {
+					names = append(names, strings.TrimPrefix(source.Name, test.dir+string(filepath.Separator)))
+				}
+				if !reflect.DeepEqual(names, test.sources) {
+					t.Errorf("sources: got %q, want %q", names, test.sources)
+				}
+				var device keyPerFileDevice
+				var resultMap ResultMap
+				resultMap, err = ReadConfigSources(&device, "Name", sources...)
+				if test.result != nil && !reflect.DeepEqual(resultMap, test.result) {
+					t.Errorf("got %+v, want %+v", resultMap, test.result)
+				}
+			}
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
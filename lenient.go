@@ -0,0 +1,139 @@
+package json_configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON config files are strict JSON, unless named .jsonc or .json5
+// - Set LenientJSON to also accept // and /* */ comments, trailing commas, unquoted keys
+// and single-quoted strings in .json files
+var LenientJSON bool
+
+// Convert lenient JSON to strict JSON, keeping every line where it was
+// - Comments and trailing commas become spaces, so a line number in the result is the line in the original
+func lenientToJSON(b []byte) []byte {
+	var out bytes.Buffer
+	var i, j int
+
+	for i = 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"':
+			// Double-quoted string, copied as is
+			out.WriteByte(c)
+			for i++; i < len(b) && b[i] != '"' && b[i] != '\n'; i++ {
+				if b[i] == '\\' && i+1 < len(b) {
+					out.WriteByte(b[i])
+					i++
+				}
+				out.WriteByte(b[i])
+			}
+			if i < len(b) {
+				out.WriteByte(b[i])
+			}
+		case c == '\'':
+			// Single-quoted string, as a double-quoted string
+			out.WriteByte('"')
+			for i++; i < len(b) && b[i] != '\'' && b[i] != '\n'; i++ {
+				switch {
+				case b[i] == '\\' && i+1 < len(b) && b[i+1] == '\'':
+					out.WriteByte('\'')
+					i++
+				case b[i] == '\\' && i+1 < len(b):
+					out.WriteByte(b[i])
+					i++
+					out.WriteByte(b[i])
+				case b[i] == '"':
+					out.WriteString(`\"`)
+				default:
+					out.WriteByte(b[i])
+				}
+			}
+			if i < len(b) && b[i] == '\'' {
+				out.WriteByte('"')
+			} else if i < len(b) {
+				out.WriteByte(b[i])
+			}
+		case c == '/' && i+1 < len(b) && (b[i+1] == '/' || b[i+1] == '*'):
+			i = blankComment(b, i, &out) - 1
+		case c == ',':
+			// A trailing comma is dropped
+			j = nextSignificant(b, i+1)
+			if j < len(b) && (b[j] == '}' || b[j] == ']') {
+				out.WriteByte(' ')
+			} else {
+				out.WriteByte(c)
+			}
+		case isIdentStart(c):
+			// An unquoted key is quoted, other words such as true are copied
+			for j = i; j < len(b) && (isIdentStart(b[j]) || b[j] >= '0' && b[j] <= '9'); j++ {
+			}
+			word := b[i:j]
+			if k := nextSignificant(b, j); k < len(b) && b[k] == ':' {
+				out.WriteByte('"')
+				out.Write(word)
+				out.WriteByte('"')
+			} else {
+				out.Write(word)
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// Write a comment starting at b[i] as spaces, keeping newlines, and return the index after it
+func blankComment(b []byte, i int, out *bytes.Buffer) int {
+	block := b[i+1] == '*'
+	out.WriteString("  ")
+	for i += 2; i < len(b); i++ {
+		if block && b[i] == '*' && i+1 < len(b) && b[i+1] == '/' {
+			out.WriteString("  ")
+			return i + 2
+		}
+		if !block && b[i] == '\n' {
+			return i
+		}
+		if b[i] == '\n' || b[i] == '\r' {
+			out.WriteByte(b[i])
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+	return i
+}
+
+// Index of the next character that isn't whitespace or in a comment
+func nextSignificant(b []byte, i int) int {
+	var discard bytes.Buffer
+
+	for i < len(b) {
+		switch {
+		case b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r':
+			i++
+		case b[i] == '/' && i+1 < len(b) && (b[i+1] == '/' || b[i+1] == '*'):
+			i = blankComment(b, i, &discard)
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+}
+
+// Add the line number to a JSON syntax error, for content where lines match the original
+func syntaxErrorLine(b []byte, err error) error {
+	// Offset is just after the bad character, which may itself be a newline
+	if syntaxErr, ok := err.(*json.SyntaxError); ok && syntaxErr.Offset > 0 && syntaxErr.Offset <= int64(len(b)) {
+		line := 1 + bytes.Count(b[:syntaxErr.Offset-1], []byte("\n"))
+		return fmt.Errorf("line %d: %v", line, err)
+	}
+	return err
+}
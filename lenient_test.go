@@ -0,0 +1,67 @@
+package json_configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLenientToJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		result string
+	}{
+		{"line comment", "{\n// device\n\"name\": \"Fan\" // trailing\n}", `{"name": "Fan"}`},
+		{"block comment", "{/* a\n  b */\"name\": \"Fan\"}", `{"name": "Fan"}`},
+		{"comment markers in strings", `{"url": "http://host//a/*b*/", 'path': '//c'}`, `{"url": "http://host//a/*b*/", "path": "//c"}`},
+		{"escaped quote in string", `{"a": "x\"y // z", "b": 1}`, `{"a": "x\"y // z", "b": 1}`},
+		{"trailing commas", "{\"a\": [1, 2,], \"b\": {\"c\": 3,},}", `{"a": [1, 2], "b": {"c": 3}}`},
+		{"trailing comma before a comment", "[1, // last\n]", `[1]`},
+		{"trailing comma before a block comment", "[1, /* , */ ]", `[1]`},
+		{"comma in a string", `["a,", "]"]`, `["a,", "]"]`},
+		{"unquoted keys", `{name: "Fan", $include: "a.json", port_2: 1, _x: true}`, `{"name": "Fan", "$include": "a.json", "port_2": 1, "_x": true}`},
+		{"unquoted key before a comment", "{name /* id */ : 'Fan'}", `{"name": "Fan"}`},
+		{"literals are not keys", `{"a": true, "b": false, "c": null, "d": 1e5}`, `{"a": true, "b": false, "c": null, "d": 1e5}`},
+		{"single quotes", `{'a': 'it\'s', 'b': 'say "hi"', 'c': 'tab\t'}`, `{"a": "it's", "b": "say \"hi\"", "c": "tab\t"}`},
+		{"double quotes keep apostrophes", `{"a": "it's"}`, `{"a": "it's"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := lenientToJSON([]byte(test.input))
+			if bytes.Count(out, []byte("\n")) != strings.Count(test.input, "\n") {
+				t.Errorf("lines not kept: %q", out)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", out, err)
+			}
+			if err := json.Unmarshal([]byte(test.result), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLenientJSONErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"{\n/* a\nb */\n\"name\": :\n}", "line 4:"},
+		{"{\n'name': 'Fan\n}", "line 2:"},
+		{"{\"name\": \"Fan\" /* unterminated", "unexpected end of JSON input"},
+	}
+	for _, test := range tests {
+		_, err := JSONDecoder{Lenient: true}.Decode([]byte(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error containing %q, got %v", test.input, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package json_configs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Field lifecycle tags, as a data object evolves
+// - `deprecated:"use accessory instead"` warns when the field is set, naming the file and line
+// - `removed:"v3"` reports an error when the field is set, and the value is not used
+// - `replacedBy:"accessory"` names the field replacing a deprecated one, used if AutoRename is set
+
+// Message for a deprecated field, such as "parameter brand is deprecated, use accessory instead"
+func deprecatedMessage(param reflect.StructField, key string) string {
+	reason := param.Tag.Get("deprecated")
+	if len(reason) > 0 {
+		return fmt.Sprintf("parameter %s is deprecated, %s", key, reason)
+	}
+	return fmt.Sprintf("parameter %s is deprecated", key)
+}
+
+// Move settings of deprecated fields to their replacement field, when AutoRename is set
+// - An element that also sets the replacement keeps both, so the deprecated setting is still reported
+func renameDeprecated(st reflect.Type, parsedMap ParsedMap) {
+	var elementId, replacedBy, newKey string
+	var line, i int
+	var ok bool
+
+	if !AutoRename {
+		return
+	}
+	fields := newFieldKeys(st)
+
+	for elementId = range parsedMap {
+		for _, parsed := range parsedMap[elementId] {
+			values := fields.elementValues(parsed.ElementMap)
+			for i = 0; i < st.NumField(); i++ {
+				param := st.Field(i)
+				if replacedBy, ok = param.Tag.Lookup("replacedBy"); !ok || len(values[i]) == 0 {
+					continue
+				}
+				fk, _, found := fields.lookup(replacedBy)
+				if !found {
+					panic(fmt.Errorf("field %s replacedBy %s, not found", param.Name, replacedBy))
+				}
+				if len(values[fk.index]) > 0 {
+					continue
+				}
+
+				kv := values[i][0]
+				newKey = keyName(st.Field(fk.index))
+				parsed.ElementMap[newKey] = kv.value
+				delete(parsed.ElementMap, kv.key)
+				if line, ok = parsed.Lines[kv.key]; ok {
+					parsed.Lines[newKey] = line
+				}
+				warn(fmt.Sprintf("setting for %s, %s, moved to %s [%s]",
+					elementId, deprecatedMessage(param, kv.key), newKey, parsed.KeyLocation(newKey)))
+			}
+		}
+	}
+}
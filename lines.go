@@ -0,0 +1,74 @@
+package json_configs
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Line number of each key in a JSON config, by element position
+// - Position is 0 for a single element, 1...N for an array of N elements, as in Parsed{}
+// - Returns what was found before any syntax error, which is reported when the file is parsed
+func keyLines(b []byte) (lines map[int]map[string]int) {
+	var tok json.Token
+	var err error
+	var offset, counted int
+	var position int
+
+	// Open objects and arrays, and whether an object expects a key next
+	type frame struct {
+		object    bool
+		expectKey bool
+	}
+	var stack []frame
+
+	lines = make(map[int]map[string]int)
+	line := 1
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		if tok, err = dec.Token(); err != nil {
+			return
+		}
+		offset = int(dec.InputOffset())
+		line += bytes.Count(b[counted:offset], []byte("\n"))
+		counted = offset
+
+		// Each value at the top of an array is the next element
+		if len(stack) == 1 && !stack[0].object {
+			if delim, ok := tok.(json.Delim); !ok || delim == '{' || delim == '[' {
+				position++
+			}
+		}
+
+		top := len(stack) - 1
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				if top >= 0 && stack[top].object {
+					stack[top].expectKey = true
+				}
+				stack = append(stack, frame{object: t == '{', expectKey: true})
+			case '}', ']':
+				stack = stack[:top]
+			}
+		case string:
+			if top >= 0 && stack[top].object && stack[top].expectKey {
+				stack[top].expectKey = false
+
+				// Keys of elements, in a single element or top level array
+				if top == 0 || (top == 1 && !stack[0].object) {
+					if lines[position] == nil {
+						lines[position] = make(map[string]int)
+					}
+					lines[position][t] = line
+				}
+			} else if top >= 0 && stack[top].object {
+				stack[top].expectKey = true
+			}
+		default:
+			if top >= 0 && stack[top].object {
+				stack[top].expectKey = true
+			}
+		}
+	}
+}
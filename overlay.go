@@ -0,0 +1,426 @@
+package json_configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Overlay files patch the elements read from the other config files
+// - A merge patch (RFC 7386) is a JSON object of element Ids, such as {"Fan": {"color": null}}
+// - A JSON Patch (RFC 6902) is an array of operations, with paths starting at the element Id, such as "/Fan/tags/0"
+const (
+	MergePatchSuffix = ".merge.json"
+	JSONPatchSuffix  = ".patch.json"
+)
+
+// Check if a file is an overlay, by suffix
+func isOverlay(filename string) bool {
+	return strings.HasSuffix(filename, MergePatchSuffix) || strings.HasSuffix(filename, JSONPatchSuffix)
+}
+
+// Apply overlay files in order, to the elements in parsedMap
+// - Each overlay is a layer above all config files, so the values it sets override without conflict
+// - Settings an overlay deletes are removed from every file the element Id was found in
+func applyOverlays(fsys fileSystem, parsedMap ParsedMap, overlays []FileDetail, layer int, idName, idTag string, errList *[]string) {
+	var b []byte
+	var err error
+	var patch, patched interface{}
+	var file FileDetail
+	var elementId, key string
+	var parsedArr []Parsed
+	var v interface{}
+	var ok bool
+
+	// Element Id is set in new elements by json tag, or field name
+	idKey := idName
+	if len(idTag) > 0 {
+		idKey = idTag
+	}
+
+	for _, file = range overlays {
+		if b, err = fsys.readFile(file.Name); err != nil {
+			*errList = append(*errList, fmt.Sprintf("reading file: %v", err))
+			continue
+		}
+		patch = nil
+		if err = json.Unmarshal(b, &patch); err != nil {
+			*errList = append(*errList, fmt.Sprintf("%v, skipping [%s]", err, file.Name))
+			continue
+		}
+
+		// Collapse each element Id into a document, as the patch target
+		doc := make(map[string]interface{})
+		for elementId, parsedArr = range parsedMap {
+			doc[elementId] = effectiveMap(parsedArr)
+		}
+
+		if strings.HasSuffix(file.Name, MergePatchSuffix) {
+			if _, ok = patch.(map[string]interface{}); !ok {
+				*errList = append(*errList, fmt.Sprintf("merge patch must be a JSON object of element Ids, skipping [%s]",
+					file.DistinctName))
+				continue
+			}
+			patched = mergePatch(copyValue(doc), patch)
+		} else {
+			patched, err = applyJSONPatch(copyValue(doc), patch)
+			if err != nil {
+				*errList = append(*errList, fmt.Sprintf("patch failed, %v, skipping [%s]", err, file.DistinctName))
+				continue
+			}
+		}
+		patchedDoc, isMap := patched.(map[string]interface{})
+		if !isMap {
+			*errList = append(*errList, fmt.Sprintf("patch result must be a JSON object of element Ids, skipping [%s]",
+				file.DistinctName))
+			continue
+		}
+		if Debug {
+			log.Printf("Applying overlay [%s]", file.Name)
+		}
+
+		// Compare before and after, to update elements
+		for elementId, v = range patchedDoc {
+			patchedMap, isMap := v.(map[string]interface{})
+			if !isMap {
+				*errList = append(*errList, fmt.Sprintf("patched element %s must be a JSON object, skipping [%s]",
+					elementId, file.DistinctName))
+				continue
+			}
+			docMap, _ := doc[elementId].(map[string]interface{})
+
+			// Remove deleted settings from every file for the element
+			for key = range docMap {
+				if _, ok = patchedMap[key]; !ok {
+					for _, parsed := range parsedMap[elementId] {
+						delete(parsed.ElementMap, key)
+					}
+				}
+			}
+
+			// Changed and added settings are in the overlay's own layer
+			changed := make(ElementMap)
+			for key, v = range patchedMap {
+				if !reflect.DeepEqual(v, docMap[key]) {
+					changed[key] = v
+				}
+			}
+			if docMap == nil {
+				changed[idKey] = elementId
+			}
+			if len(changed) > 0 {
+				parsedMap[elementId] = append(parsedMap[elementId], Parsed{
+					FileName:     file.Name,
+					DistinctName: file.DistinctName,
+					Layer:        layer,
+					ElementMap:   changed,
+				})
+			}
+		}
+
+		// Remove deleted elements
+		for elementId = range doc {
+			if _, ok = patchedDoc[elementId]; !ok {
+				delete(parsedMap, elementId)
+			}
+		}
+		layer++
+	}
+}
+
+// Collapse settings for an element, in order, so later settings override earlier ones
+func effectiveMap(parsedArr []Parsed) (elementMap map[string]interface{}) {
+	var key string
+	var v interface{}
+
+	elementMap = make(map[string]interface{})
+	for _, parsed := range parsedArr {
+		for key, v = range parsed.ElementMap {
+			elementMap[key] = v
+		}
+	}
+	return
+}
+
+// Apply a JSON merge patch to target, as in RFC 7386
+func mergePatch(target, patch interface{}) interface{} {
+	var key string
+	var v interface{}
+
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+	for key, v = range patchMap {
+		if v == nil {
+			delete(targetMap, key)
+		} else {
+			targetMap[key] = mergePatch(targetMap[key], v)
+		}
+	}
+	return targetMap
+}
+
+// Apply JSON Patch operations to doc, as in RFC 6902
+// - If any operation fails, err names it by index, and the patch is not applied
+func applyJSONPatch(doc interface{}, patch interface{}) (result interface{}, err error) {
+	var tokens, fromTokens []string
+	var value interface{}
+	var opName, path, from string
+	var i int
+	var ok bool
+
+	ops, ok := patch.([]interface{})
+	if !ok {
+		err = fmt.Errorf("JSON Patch must be an array of operations")
+		return
+	}
+
+	result = doc
+	for i = range ops {
+		op, isMap := ops[i].(map[string]interface{})
+		if !isMap {
+			err = fmt.Errorf("operation #%d: must be a JSON object", i+1)
+			return
+		}
+		opName, _ = op["op"].(string)
+		path, _ = op["path"].(string)
+		from, _ = op["from"].(string)
+
+		tokens, err = pointerTokens(path)
+		if err == nil {
+			switch opName {
+			case "add", "replace", "test":
+				if value, ok = op["value"]; !ok {
+					err = fmt.Errorf("missing value")
+					break
+				}
+				if opName == "test" {
+					var found interface{}
+					if found, err = pointerGet(result, tokens); err == nil && !reflect.DeepEqual(found, value) {
+						err = fmt.Errorf("test failed, value is %v", found)
+					}
+				} else {
+					result, err = pointerSet(result, tokens, copyValue(value), opName == "add")
+				}
+			case "remove":
+				result, _, err = pointerRemove(result, tokens)
+			case "move", "copy":
+				if fromTokens, err = pointerTokens(from); err != nil {
+					break
+				}
+				if opName == "move" {
+					if strings.HasPrefix(path+"/", from+"/") && path != from {
+						err = fmt.Errorf("cannot move %s into itself", from)
+						break
+					}
+					if result, value, err = pointerRemove(result, fromTokens); err != nil {
+						break
+					}
+				} else {
+					if value, err = pointerGet(result, fromTokens); err != nil {
+						break
+					}
+					value = copyValue(value)
+				}
+				result, err = pointerSet(result, tokens, value, true)
+			default:
+				err = fmt.Errorf("unknown op %q", opName)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("operation #%d (%s %s): %v", i+1, opName, path, err)
+			return
+		}
+	}
+	return
+}
+
+// Split a JSON Pointer into reference tokens, as in RFC 6901
+func pointerTokens(path string) (tokens []string, err error) {
+	var i int
+
+	if len(path) == 0 {
+		return
+	}
+	if path[0] != '/' {
+		err = fmt.Errorf("path %q must start with /", path)
+		return
+	}
+	tokens = strings.Split(path[1:], "/")
+	for i = range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tokens[i], "~1", "/", -1), "~0", "~", -1)
+	}
+	return
+}
+
+// Index of a JSON Pointer token into an array of length n, where n itself is allowed to append
+func pointerIndex(token string, n int, allowEnd bool) (i int, err error) {
+	if token == "-" && allowEnd {
+		return n, nil
+	}
+	i, err = strconv.Atoi(token)
+	if err != nil || i < 0 || i > n || (i == n && !allowEnd) || (len(token) > 1 && token[0] == '0') {
+		err = fmt.Errorf("array index %s out of range", token)
+	}
+	return
+}
+
+// Get the value at a JSON Pointer
+func pointerGet(doc interface{}, tokens []string) (value interface{}, err error) {
+	var token string
+	var i int
+	var ok bool
+
+	value = doc
+	for _, token = range tokens {
+		switch d := value.(type) {
+		case map[string]interface{}:
+			if value, ok = d[token]; !ok {
+				err = fmt.Errorf("path not found")
+				return
+			}
+		case []interface{}:
+			if i, err = pointerIndex(token, len(d), false); err != nil {
+				return
+			}
+			value = d[i]
+		default:
+			err = fmt.Errorf("path not found")
+			return
+		}
+	}
+	return
+}
+
+// Set the value at a JSON Pointer, returning the updated doc
+// - If add, the value is inserted into arrays and may create an object member, otherwise it must replace
+func pointerSet(doc interface{}, tokens []string, value interface{}, add bool) (result interface{}, err error) {
+	var child interface{}
+	var i int
+	var ok bool
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token := tokens[0]
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok = d[token]
+		if len(tokens) == 1 {
+			if !ok && !add {
+				err = fmt.Errorf("path not found")
+				return
+			}
+			d[token] = value
+			return d, nil
+		}
+		if !ok {
+			err = fmt.Errorf("path not found")
+			return
+		}
+		if child, err = pointerSet(child, tokens[1:], value, add); err != nil {
+			return
+		}
+		d[token] = child
+		return d, nil
+	case []interface{}:
+		if len(tokens) == 1 && add {
+			if i, err = pointerIndex(token, len(d), true); err != nil {
+				return
+			}
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = value
+			return d, nil
+		}
+		if i, err = pointerIndex(token, len(d), false); err != nil {
+			return
+		}
+		if len(tokens) == 1 {
+			d[i] = value
+			return d, nil
+		}
+		if d[i], err = pointerSet(d[i], tokens[1:], value, add); err != nil {
+			return
+		}
+		return d, nil
+	}
+	err = fmt.Errorf("path not found")
+	return
+}
+
+// Remove the value at a JSON Pointer, returning the updated doc and the value removed
+func pointerRemove(doc interface{}, tokens []string) (result, removed interface{}, err error) {
+	var child interface{}
+	var i int
+	var ok bool
+
+	if len(tokens) == 0 {
+		err = fmt.Errorf("cannot remove the whole document")
+		return
+	}
+	token := tokens[0]
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		if child, ok = d[token]; !ok {
+			err = fmt.Errorf("path not found")
+			return
+		}
+		if len(tokens) == 1 {
+			delete(d, token)
+			return d, child, nil
+		}
+		if child, removed, err = pointerRemove(child, tokens[1:]); err != nil {
+			return
+		}
+		d[token] = child
+		return d, removed, nil
+	case []interface{}:
+		if i, err = pointerIndex(token, len(d), false); err != nil {
+			return
+		}
+		if len(tokens) == 1 {
+			removed = d[i]
+			return append(d[:i:i], d[i+1:]...), removed, nil
+		}
+		if d[i], removed, err = pointerRemove(d[i], tokens[1:]); err != nil {
+			return
+		}
+		return d, removed, nil
+	}
+	err = fmt.Errorf("path not found")
+	return
+}
+
+// Deep copy a parsed JSON value, so patching doesn't change the original
+func copyValue(value interface{}) interface{} {
+	var key string
+	var v interface{}
+	var i int
+
+	switch d := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(d))
+		for key, v = range d {
+			m[key] = copyValue(v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(d))
+		for i, v = range d {
+			a[i] = copyValue(v)
+		}
+		return a
+	}
+	return value
+}
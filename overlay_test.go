@@ -0,0 +1,240 @@
+package json_configs
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeTestJSON(t *testing.T, s string) (v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid test JSON %s: %v", s, err)
+	}
+	return
+}
+
+// Examples from RFC 6902 Appendix A, then cases for the -, leading zero and error paths
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		patch  string
+		result string
+		err    string
+	}{
+		{
+			name:   "A.1 adding an object member",
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			result: `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:   "A.2 adding an array element",
+			doc:    `{"foo": ["bar", "baz"]}`,
+			patch:  `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			result: `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:   "A.3 removing an object member",
+			doc:    `{"baz": "qux", "foo": "bar"}`,
+			patch:  `[{"op": "remove", "path": "/baz"}]`,
+			result: `{"foo": "bar"}`,
+		},
+		{
+			name:   "A.4 removing an array element",
+			doc:    `{"foo": ["bar", "qux", "baz"]}`,
+			patch:  `[{"op": "remove", "path": "/foo/1"}]`,
+			result: `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:   "A.5 replacing a value",
+			doc:    `{"baz": "qux", "foo": "bar"}`,
+			patch:  `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			result: `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:   "A.6 moving a value",
+			doc:    `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:  `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			result: `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:   "A.7 moving an array element",
+			doc:    `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:  `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			result: `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:   "A.8 testing a value, success",
+			doc:    `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch:  `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			result: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value, error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   "operation #1 (test /baz): test failed, value is qux",
+		},
+		{
+			name:   "A.10 adding a nested member object",
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			result: `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:   "A.11 ignoring unrecognized elements",
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			result: `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   "operation #1 (add /baz/bat): path not found",
+		},
+		{
+			name:   "A.14 ~ escape ordering",
+			doc:    `{"/": 9, "~1": 10}`,
+			patch:  `[{"op": "test", "path": "/~01", "value": 10}]`,
+			result: `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			err:   "operation #1 (test /~01): test failed",
+		},
+		{
+			name:   "A.16 adding an array value",
+			doc:    `{"foo": ["bar"]}`,
+			patch:  `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			result: `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:   "copy is a deep copy",
+			doc:    `{"foo": {"bar": 1}}`,
+			patch:  `[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			result: `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+		{
+			name:   "replace the whole document",
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "replace", "path": "", "value": {"baz": "qux"}}]`,
+			result: `{"baz": "qux"}`,
+		},
+		{
+			name:  "- only appends",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "replace", "path": "/foo/-", "value": "baz"}]`,
+			err:   "operation #1 (replace /foo/-): array index - out of range",
+		},
+		{
+			name:  "leading zero index",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/01", "value": "qux"}]`,
+			err:   "operation #1 (add /foo/01): array index 01 out of range",
+		},
+		{
+			name:  "index past the end",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/2", "value": "qux"}]`,
+			err:   "array index 2 out of range",
+		},
+		{
+			name:  "error names the failed operation",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": 1}, {"op": "remove", "path": "/qux"}]`,
+			err:   "operation #2 (remove /qux): path not found",
+		},
+		{
+			name:  "move into itself",
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			err:   "operation #1 (move /foo/bar/baz): cannot move /foo into itself",
+		},
+		{
+			name:  "missing value",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz"}]`,
+			err:   "operation #1 (add /baz): missing value",
+		},
+		{
+			name:  "unknown op",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "merge", "path": "/foo"}]`,
+			err:   `operation #1 (merge /foo): unknown op "merge"`,
+		},
+		{
+			name:  "path without /",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "foo"}]`,
+			err:   `path "foo" must start with /`,
+		},
+		{
+			name:  "operation not an object",
+			doc:   `{"foo": "bar"}`,
+			patch: `["add"]`,
+			err:   "operation #1: must be a JSON object",
+		},
+		{
+			name:  "patch not an array",
+			doc:   `{"foo": "bar"}`,
+			patch: `{"op": "add", "path": "/baz", "value": 1}`,
+			err:   "JSON Patch must be an array of operations",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := applyJSONPatch(decodeTestJSON(t, test.doc), decodeTestJSON(t, test.patch))
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := decodeTestJSON(t, test.result); !reflect.DeepEqual(result, want) {
+				t.Errorf("got %v, want %v", result, want)
+			}
+		})
+	}
+}
+
+// Examples from RFC 7386 Appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		result := mergePatch(decodeTestJSON(t, test.target), decodeTestJSON(t, test.patch))
+		if want := decodeTestJSON(t, test.result); !reflect.DeepEqual(result, want) {
+			t.Errorf("%s patched by %s: got %v, want %v", test.target, test.patch, result, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package json_configs
 
 import (
 	"fmt"
+	"log"
 	"reflect"
 	"strconv"
 	"time"
@@ -10,120 +11,147 @@ import (
 // Parse config dataMap entries corresponding to data object (st, sv) fields
 func parseConfig(st reflect.Type, sv reflect.Value, parsedMap ParsedMap, errList *[]string) (resultMap ResultMap) {
 	var err error
-	var elementId, filename, tagName, paramValue, paramType string
+	var elementId, filename string
 	var v interface{}
+	var rv reflect.Value
 	var parsedArr []Parsed
-	var dur time.Duration
-	var date time.Time
-	var f float64
-	var n int64
 	var i int
 	var ok, clear bool
 
-	// Map for json param names to tag names
-	param2tag := make(map[string]string)
-	for i = 0; i < st.NumField(); i++ {
-		param := st.Field(i)
-		tagName, ok = param.Tag.Lookup("json")
-		if ok {
-			param2tag[param.Name] = tagName
-		}
-	}
+	// Keys for each field, including aliases
+	fields := newFieldKeys(st)
+
+	// Optional field to capture keys not used by other fields
+	remain := remainField(st)
 
 	// If multiple results, we will have to clear 'data' object each iteration
 	clear = len(parsedMap) > 1
-	
+
 	resultMap = make(ResultMap)
 	for elementId, parsedArr = range parsedMap {
 
 		// Make map of parameter names that will need to be reset after parsing element
 		clearParamMap := make(map[string]bool)
 
+		// Make map of the level each parameter was set at, for null values
+		setLevelMap := make(map[string]level)
+
+		// Make map of keys that aren't fields, merged across files, with the level each was set at
+		extras := make(map[string]interface{})
+		extraLevelMap := make(map[string]level)
+
 		for _, parsed := range parsedArr {
-			if parsed.Position == 0 {
-				filename = parsed.DistinctName
-			} else {
-				filename = fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
+			filename = parsed.Location()
+
+			// Capture keys that aren't fields, later layers override earlier ones, validateParameters() reports conflicts
+			if remain >= 0 {
+				for key, v := range parsed.ElementMap {
+					if _, _, ok = fields.lookup(key); ok || key == ExtendsKey {
+						continue
+					}
+					if v == nil && NullDeletes {
+						// as for fields, a value at the same level is not deleted
+						if setLevel, set := extraLevelMap[key]; !set || setLevel != levelOf(parsed) {
+							delete(extras, key)
+							delete(extraLevelMap, key)
+						}
+					} else {
+						extras[key] = v
+						extraLevelMap[key] = levelOf(parsed)
+					}
+				}
 			}
 
 			// Iterate through element data fields, parse into correct type
+			values := fields.elementValues(parsed.ElementMap)
 			for i = 0; i < st.NumField(); i++ {
+				if i == remain {
+					continue
+				}
 				param := st.Field(i)
 
-				// lookup in ElementMap by tag name first, then by param name
-				tagName, ok = param2tag[param.Name]
+				// lookup in ElementMap by tag name first, then by param name, then aliases
+				ok = len(values[i]) > 0
 				if ok {
-					v, ok = parsed.ElementMap[tagName]
+					v = values[i][0].value
 				}
-				if !ok {
-					v, ok = parsed.ElementMap[param.Name]
+
+				// Warn for deprecated fields and aliases, once where the element itself is parsed
+				for _, kv := range values[i] {
+					if len(parsed.InheritedFrom) > 0 {
+						break
+					}
+					if _, deprecated := param.Tag.Lookup("deprecated"); deprecated {
+						warn(fmt.Sprintf("setting for %s, %s [%s]",
+							elementId, deprecatedMessage(param, kv.key), parsed.KeyLocation(kv.key)))
+					} else if kv.rank >= 2 {
+						warn(fmt.Sprintf("setting for %s, parameter %s is a deprecated alias of %s [%s]",
+							elementId, kv.key, keyName(param), parsed.KeyLocation(kv.key)))
+					}
 				}
 
-				if ok {
-					paramValue = fmt.Sprintf("%v", v)
-					paramType = param.Type.Name()
+				// A removed field is an error, and not set
+				if version, removed := param.Tag.Lookup("removed"); removed && ok {
+					if len(parsed.InheritedFrom) == 0 {
+						*errList = append(*errList,
+							fmt.Sprintf("setting for %s invalid, parameter %s was removed in %s [%s]",
+								elementId, values[i][0].key, version, parsed.KeyLocation(values[i][0].key)))
+					}
+					continue
+				}
+
+				if ok && v == nil {
+					// JSON null unsets a pointer, or optionally deletes the value from a lower level
+					if param.Type.Kind() != reflect.Ptr && !NullDeletes {
+						*errList = append(*errList,
+							fmt.Sprintf("setting for %s invalid, parameter %s: null for %s [%s]",
+								elementId, param.Name, param.Type, filename))
+						continue
+					}
+					if setLevel, set := setLevelMap[param.Name]; set && setLevel == levelOf(parsed) {
+						// a value at the same level is not deleted, a conflict in order only
+						continue
+					}
+					clearParamMap[param.Name] = true
+					sv.Field(i).Set(reflect.Zero(param.Type))
+					delete(setLevelMap, param.Name)
+					if Debug {
+						log.Printf("Clearing %s parameter %s from [%s]", elementId, param.Name, filename)
+					}
+				} else if ok {
 					clearParamMap[param.Name] = true
 
-					switch paramType {
-					case "string":
-						sv.Field(i).SetString(paramValue)
-					case "float64":
-						f, err = strconv.ParseFloat(paramValue, 64)
-						if err != nil {
-							*errList = append(*errList,
-								fmt.Sprintf("setting for %s invalid, parameter %s: float %s [%s]",
-									elementId, param.Name, paramValue, filename))
-							continue
-						}
-						sv.Field(i).SetFloat(f)
-					case "int", "int64":
-						n, err = strconv.ParseInt(paramValue, 10, 64)
-						if err != nil {
+					// Optionally require the JSON type to match the field type, where the format has types
+					if StrictTypes && !parsed.Untyped {
+						expected := expectedJSONType(param)
+						if len(expected) > 0 && expected != jsonType(v) {
 							*errList = append(*errList,
-								fmt.Sprintf("setting for %s invalid, parameter %s: integer %s [%s]",
-									elementId, param.Name, paramValue, filename))
+								fmt.Sprintf("setting for %s invalid, parameter %s: expected JSON %s, found %s [%s]",
+									elementId, param.Name, expected, jsonType(v), filename))
 							continue
 						}
-						sv.Field(i).SetInt(n)
-					case "bool":
-						ok, err = strconv.ParseBool(paramValue)
-						if err != nil {
-							*errList = append(*errList,
-								fmt.Sprintf("setting for %s invalid, parameter %s: boolean %s [%s]",
-									elementId, param.Name, paramValue, filename))
-							continue
-						}
-						sv.Field(i).SetBool(ok)
-					case "Duration":
-						dur, err = time.ParseDuration(paramValue)
-						if err != nil {
-							*errList = append(*errList,
-								fmt.Sprintf("setting for %s invalid, parameter %s: duration %s [%s]",
-									elementId, param.Name, paramValue, filename))
-							continue
-						}
-						sv.Field(i).Set(reflect.ValueOf(dur))
-					case "Time":
-						date, err = time.Parse("2006-01-02T15:04:05Z", paramValue)
-						if err != nil {
-							date, err = time.Parse("2006-01-02", paramValue)
-						}
-						if err != nil {
-							*errList = append(*errList,
-								fmt.Sprintf("setting for %s invalid, parameter %s: date %s [%s]",
-									elementId, param.Name, paramValue, filename))
-							continue
-						}
-						sv.Field(i).Set(reflect.ValueOf(date))
-					default:
+					}
+
+					rv, err = decodeParam(param, valueString(v))
+					if err != nil {
 						*errList = append(*errList,
-							fmt.Sprintf("setting for %s invalid, parameter %s: unsupported type %s [%s]",
-								elementId, param.Name, paramType, filename))
+							fmt.Sprintf("setting for %s invalid, parameter %s: %v [%s]",
+								elementId, param.Name, err, filename))
 						continue
 					}
+					sv.Field(i).Set(rv)
+					setLevelMap[param.Name] = levelOf(parsed)
+					if Debug {
+						log.Printf("Setting %s parameter %s from [%s]", elementId, param.Name, filename)
+					}
 				}
 			}
 		}
+		if remain >= 0 && len(extras) > 0 {
+			sv.Field(remain).Set(reflect.ValueOf(extras))
+			clearParamMap[st.Field(remain).Name] = true
+		}
+
 		// Store data object in resultMap
 		resultMap[elementId] = sv.Interface()
 
@@ -135,6 +163,185 @@ func parseConfig(st reflect.Type, sv reflect.Value, parsedMap ParsedMap, errList
 	return
 }
 
+// Level of an element's settings, where a later level overrides and the same level conflicts
+type level struct {
+	layer         int
+	inheritedFrom string
+}
+
+func levelOf(parsed Parsed) level {
+	return level{layer: parsed.Layer, inheritedFrom: parsed.InheritedFrom}
+}
+
+// Decode a parameter value into the type of data object field 'param'
+// - A pointer field is set to a newly allocated value
+func decodeParam(param reflect.StructField, paramValue string) (rv reflect.Value, err error) {
+	var dur time.Duration
+	var size ByteSize
+	var date time.Time
+	var f float64
+	var n int64
+	var b bool
+
+	if param.Type.Kind() == reflect.Ptr {
+		elemParam := param
+		elemParam.Type = param.Type.Elem()
+		rv, err = decodeParam(elemParam, paramValue)
+		if err != nil {
+			return
+		}
+		ptr := reflect.New(elemParam.Type)
+		ptr.Elem().Set(rv)
+		rv = ptr
+		return
+	}
+
+	paramType := typeName(param.Type)
+	switch paramType {
+	case "string":
+		rv = reflect.ValueOf(paramValue).Convert(param.Type)
+	case "float64":
+		f, err = strconv.ParseFloat(paramValue, 64)
+		if err != nil {
+			err = fmt.Errorf("float %s", paramValue)
+			return
+		}
+		rv = reflect.ValueOf(f).Convert(param.Type)
+	case "int", "int64":
+		n, err = strconv.ParseInt(paramValue, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("integer %s", paramValue)
+			return
+		}
+		rv = reflect.ValueOf(n).Convert(param.Type)
+	case "bool":
+		if LenientCoercion {
+			b, err = parseLenientBool(paramValue)
+		} else {
+			b, err = strconv.ParseBool(paramValue)
+		}
+		if err != nil {
+			err = fmt.Errorf("boolean %s", paramValue)
+			return
+		}
+		rv = reflect.ValueOf(b).Convert(param.Type)
+	case "Duration":
+		dur, err = time.ParseDuration(paramValue)
+		unit, hasUnit := param.Tag.Lookup("unit")
+		if err != nil && LenientCoercion && hasUnit {
+			dur, err = parseDurationUnit(paramValue, unit)
+		}
+		if err != nil {
+			err = fmt.Errorf("duration %s", paramValue)
+			return
+		}
+		rv = reflect.ValueOf(dur)
+	case "ByteSize":
+		size, err = ParseByteSize(paramValue)
+		if err != nil {
+			err = fmt.Errorf("byte size %s", paramValue)
+			return
+		}
+		rv = reflect.ValueOf(size)
+	case "Time":
+		date, err = parseTime(param.Tag, paramValue)
+		if err != nil {
+			return
+		}
+		rv = reflect.ValueOf(date)
+	default:
+		err = fmt.Errorf("unsupported type %s", paramType)
+	}
+	return
+}
+
+// Name of a field type, for decodeParam() and clearConfig()
+// - Another package's type named ByteSize is named with its package, such as main.ByteSize, so it isn't decoded as ours
+func typeName(t reflect.Type) string {
+	if t.Name() == "ByteSize" && t != reflect.TypeOf(ByteSize(0)) {
+		return t.String()
+	}
+	return t.Name()
+}
+
+// Text of a JSON value for decodeParam(), with numbers in plain decimal, such as 67108864 rather than 6.7108864e+07
+func valueString(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Canonical form of a setting, decoded into the type of field 'param', for comparing values
+// - For example, durations "5s" and "5000ms" are both "5s", and times are in UTC
+// - Returns an error for a value that doesn't decode, which parseConfig() reports
+func canonicalValue(param reflect.StructField, v interface{}) (value string, err error) {
+	var rv reflect.Value
+
+	if rv, err = decodeParam(param, valueString(v)); err != nil {
+		return
+	}
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	date, ok := rv.Interface().(time.Time)
+	if ok {
+		value = date.UTC().Format(time.RFC3339Nano)
+	} else {
+		value = fmt.Sprintf("%v", rv.Interface())
+	}
+	return
+}
+
+// JSON type expected for field 'param', when StrictTypes is set
+// - Returns "" for types without an expected JSON type, or that accept more than one
+func expectedJSONType(param reflect.StructField) string {
+	t := param.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch typeName(t) {
+	case "Duration":
+		if _, hasUnit := param.Tag.Lookup("unit"); LenientCoercion && hasUnit {
+			return ""
+		}
+		return "string"
+	case "Time", "ByteSize":
+		// Time can also be an epoch number, and ByteSize a string with suffix
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+// JSON type of a parsed value, for messages
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
 func clearConfig(st reflect.Type, sv reflect.Value, clearParamMap map[string]bool) (err error) {
 	var paramType string
 	var zeroD time.Duration
@@ -147,15 +354,17 @@ func clearConfig(st reflect.Type, sv reflect.Value, clearParamMap map[string]boo
 		param := st.Field(i)
 
 		ok = clearParamMap[param.Name]
-		if ok {
-			paramType = param.Type.Name()
+		if ok && (param.Type.Kind() == reflect.Ptr || param.Type.Kind() == reflect.Map) {
+			sv.Field(i).Set(reflect.Zero(param.Type))
+		} else if ok {
+			paramType = typeName(param.Type)
 
 			switch paramType {
 			case "string":
 				sv.Field(i).SetString("")
 			case "float64":
 				sv.Field(i).SetFloat(0)
-			case "int", "int64":
+			case "int", "int64", "ByteSize":
 				sv.Field(i).SetInt(0)
 			case "bool":
 				sv.Field(i).SetBool(false)
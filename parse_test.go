@@ -0,0 +1,229 @@
+package json_configs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type paramConfig struct {
+	Name    string
+	Port    int
+	Ratio   float64
+	Enabled bool
+	Timeout time.Duration
+	Size    ByteSize
+	Limit   *int
+}
+
+func TestDecodeParam(t *testing.T) {
+	// Another type named ByteSize, which isn't decoded as ours
+	type ByteSize string
+	var other struct{ Other ByteSize }
+
+	field := func(name string) reflect.StructField {
+		f, ok := reflect.TypeOf(paramConfig{}).FieldByName(name)
+		if !ok {
+			f, _ = reflect.TypeOf(other).FieldByName(name)
+		}
+		return f
+	}
+	limit := 3
+
+	tests := []struct {
+		field string
+		value string
+		want  interface{}
+		err   string
+	}{
+		{"Name", "Fan", "Fan", ""},
+		{"Port", "21000", 21000, ""},
+		{"Port", "1.0", nil, "integer 1.0"},
+		{"Ratio", "0.5", 0.5, ""},
+		{"Enabled", "true", true, ""},
+		{"Enabled", "yes", nil, "boolean yes"},
+		{"Timeout", "5s", 5 * time.Second, ""},
+		{"Timeout", "5", nil, "duration 5"},
+		{"Size", "64MiB", int64(64 << 20), ""},
+		{"Size", "67108864", int64(64 << 20), ""},
+		{"Size", "64XB", nil, "byte size 64XB"},
+		{"Other", "64MiB", nil, "unsupported type json_configs.ByteSize"},
+		{"Limit", "3", &limit, ""},
+	}
+	for _, test := range tests {
+		rv, err := decodeParam(field(test.field), test.value)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s %s: expected error containing %q, got %v", test.field, test.value, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", test.field, test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(rv.Interface(), reflect.ValueOf(test.want).Convert(rv.Type()).Interface()) {
+			t.Errorf("%s %s: got %v, want %v", test.field, test.value, rv.Interface(), test.want)
+		}
+	}
+
+	for name, want := range map[string]string{"Name": "string", "Port": "number", "Timeout": "string", "Size": "", "Other": "string"} {
+		if got := expectedJSONType(field(name)); got != want {
+			t.Errorf("expected JSON type of %s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	type strictDevice struct {
+		Name    string `json:"name"`
+		Port    int    `json:"port"`
+		Enabled bool   `json:"enabled"`
+	}
+
+	defer func(strict bool) { StrictTypes = strict }(StrictTypes)
+	StrictTypes = true
+
+	tests := []struct {
+		filename string
+		content  string
+		err      string
+	}{
+		{"d.json", `{"name": "Fan", "port": 1, "enabled": true}`, ""},
+		{"d.json", `{"name": "Fan", "port": "1", "enabled": true}`, "parameter Port: expected JSON number, found string [d.json]"},
+		{"d.yaml", "name: Fan\nport: 1\nenabled: 'true'\n", "parameter Enabled: expected JSON boolean, found string [d.yaml]"},
+		{"d.toml", "name = \"Fan\"\nport = 1\nenabled = true\n", ""},
+
+		// Formats with only strings are converted as usual
+		{"d.csv", "name,port,enabled\nFan,1,true\n", ""},
+		{"d.tsv", "name\tport\nFan\t1\n", ""},
+		{"d.ini", "[Fan]\nport = 1\nenabled = true\n", ""},
+		{"d.csv", "name,port\nFan,x\n", "parameter Port: integer x [d.csv:elem#2]"},
+	}
+	for _, test := range tests {
+		var device strictDevice
+		fsys := fstest.MapFS{test.filename: {Data: []byte(test.content)}}
+		resultMap, err := ReadConfigFilesFS(fsys, &device, "Name", test.filename)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", test.content, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.content, err)
+			continue
+		}
+		if resultMap["Fan"].(strictDevice).Port != 1 {
+			t.Errorf("%s: got %+v", test.content, resultMap["Fan"])
+		}
+	}
+
+	// Key-per-file settings are strings too
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Fan"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Fan", "port"), []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := KeyPerFileSources(dir, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var device strictDevice
+	resultMap, err := ReadConfigSources(&device, "Name", sources...)
+	if err != nil || resultMap["Fan"].(strictDevice).Port != 1 {
+		t.Errorf("key-per-file: got %+v, %v", resultMap, err)
+	}
+}
+
+func TestNullValues(t *testing.T) {
+	type nullDevice struct {
+		Name   string                 `json:"name"`
+		Host   string                 `json:"host"`
+		Limit  *int                   `json:"limit"`
+		Extras map[string]interface{} `json:",remain"`
+	}
+	limit := 3
+
+	tests := []struct {
+		name        string
+		nullDeletes bool
+		layers      [][]string
+		result      nullDevice
+		err         string
+	}{
+		{
+			name:   "null pointer is unset",
+			layers: [][]string{{"pointer-null.json"}},
+			result: nullDevice{Name: "Fan"},
+		},
+		{
+			name:   "null pointer in a later layer unsets",
+			layers: [][]string{{"base.json"}, {"pointer-null.json"}},
+			result: nullDevice{Name: "Fan", Host: "10.0.0.1", Extras: map[string]interface{}{"vendor": "acme"}},
+		},
+		{
+			name:   "null pointer in the same layer doesn't conflict",
+			layers: [][]string{{"base.json", "pointer-null.json"}},
+			result: nullDevice{Name: "Fan", Host: "10.0.0.1", Limit: &limit, Extras: map[string]interface{}{"vendor": "acme"}},
+		},
+		{
+			name:   "null for another field is invalid",
+			layers: [][]string{{"base.json"}, {"host-null.json"}},
+			err:    "setting for Fan invalid, parameter Host: null for string [host-null.json]",
+		},
+		{
+			name:        "NullDeletes clears a lower layer",
+			nullDeletes: true,
+			layers:      [][]string{{"base.json"}, {"host-null.json"}},
+			result:      nullDevice{Name: "Fan", Limit: &limit},
+		},
+		{
+			name:        "NullDeletes keeps a value in the same layer",
+			nullDeletes: true,
+			layers:      [][]string{{"base.json", "host-null.json"}},
+			result:      nullDevice{Name: "Fan", Host: "10.0.0.1", Limit: &limit, Extras: map[string]interface{}{"vendor": "acme"}},
+		},
+		{
+			name:        "NullDeletes clears an inherited value",
+			nullDeletes: true,
+			layers:      [][]string{{"base.json", "inherit-null.json"}},
+			result:      nullDevice{Name: "Fan2", Limit: &limit},
+		},
+	}
+
+	fsys := fsFileSystem{fsys: fstest.MapFS{
+		"base.json":         {Data: []byte(`{"name": "Fan", "host": "10.0.0.1", "limit": 3, "vendor": "acme"}`)},
+		"pointer-null.json": {Data: []byte(`{"name": "Fan", "limit": null}`)},
+		"host-null.json":    {Data: []byte(`{"name": "Fan", "host": null, "vendor": null}`)},
+		"inherit-null.json": {Data: []byte(`{"name": "Fan2", "extends": "Fan", "host": null, "vendor": null}`)},
+	}}
+
+	defer func(nullDeletes bool) { NullDeletes = nullDeletes }(NullDeletes)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var device nullDevice
+			NullDeletes = test.nullDeletes
+			resultMap, err := readConfigFiles("test", fsys, &device, "Name", test.layers)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resultMap[test.result.Name]; !reflect.DeepEqual(got, test.result) {
+				t.Errorf("got %#v, want %#v", got, test.result)
+			}
+		})
+	}
+}
@@ -1,21 +1,71 @@
 package json_configs
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"log"
-	"path/filepath"
+	"os"
 	"reflect"
+	"sort"
 	"strings"
 )
 
 var Debug bool
 
+// JSON null normally unsets only pointer fields, and is an error for other types
+// - Set NullDeletes so null clears any field, deleting a value from lower layers or inherited elements
+var NullDeletes bool
+
+// Settings are compared for conflicts after decoding into the field type, so "5s" and "5000ms" are equal
+// - Set StrictEquality so values with different JSON types also conflict, such as "21000" and 21000
+var StrictEquality bool
+
+// Settings are normally converted from any JSON type, so "port": 21000 can set a string field
+// - Set StrictTypes so the JSON type must match: number for numeric fields, boolean for bool,
+// and string for string, Duration and Time fields
+// - Settings from formats whose values are all strings, such as INI, CSV and key-per-file sources, are not checked
+var StrictTypes bool
+
+// Set LenientCoercion to accept yes/no and on/off for bool fields,
+// and numbers for Duration fields with a unit tag, such as `unit:"s"`
+var LenientCoercion bool
+
+// Keys match fields by json tag name, field name, or an alias, exactly
+// - Set CaseInsensitive so keys also match ignoring case
+var CaseInsensitive bool
+
+// Fields tagged `deprecated:"..."` are still set, with a warning
+// - Set AutoRename to move the value to the field named by a `replacedBy:"..."` tag instead
+var AutoRename bool
+
+// Warnings, such as use of a deprecated alias, are logged unless WarningHandler is set
+var WarningHandler func(warning string)
+
+func warn(warning string) {
+	if WarningHandler != nil {
+		WarningHandler(warning)
+	} else {
+		log.Printf("Warning: %s", warning)
+	}
+}
+
 // Read a single config file, return a struct, where 'data' is a pointer to that struct
+// - A filename of "-" reads standard input
 func ReadConfigFile(data interface{}, filename string) (err error) {
+	fsys, filenames := stdinFileSystem([]string{filename})
+	return readConfigFile("ReadConfigFile", fsys, data, filenames[0])
+}
+
+// Read a single config file from fsys, such as an embed.FS, where 'data' is a pointer to a struct
+func ReadConfigFileFS(fsys fs.FS, data interface{}, filename string) (err error) {
+	return readConfigFile("ReadConfigFileFS", fsFileSystem{fsys: fsys}, data, filename)
+}
+
+// Read a single config file into a struct
+func readConfigFile(caller string, fsys fileSystem, data interface{}, filename string) (err error) {
 	var b []byte
 	var errList []string
+	var doc Document
 	var config interface{}
 	var k string
 	var i int
@@ -23,31 +73,37 @@ func ReadConfigFile(data interface{}, filename string) (err error) {
 	// Make sure data is a pointer to a struct
 	k = reflect.TypeOf(data).Kind().String()
 	if k != "ptr" {
-		err = fmt.Errorf("ReadConfigFile: 'data' must be ptr, not %s", k)
+		err = fmt.Errorf("%s: 'data' must be ptr, not %s", caller, k)
 		panic(err)
 	}
 	st := reflect.TypeOf(data).Elem()
 	sv := reflect.ValueOf(data).Elem()
 
-	_, err = ValidateFile(filename)
+	// Gzip files and archive members are read like any other file
+	fsys = withArchives(fsys)
+	_, err = validateFile(fsys, filename)
 	if err != nil {
 		return
 	}
 
-	if b, err = ioutil.ReadFile(filename); err != nil {
+	if b, err = fsys.readFile(filename); err != nil {
 		err = fmt.Errorf("reading file: %v", err)
 		return
 	}
 
 	// Parse config file into map[string]interface{}
-	err = json.Unmarshal(b, &config)
+	doc, err = decodeConfig(filename, b)
 	if err != nil {
 		err = fmt.Errorf("%v [%s]", err, filename)
 		return
 	}
+	config = doc.Config
 
 	// Each file can contain a single element of type 'data'
-	k = reflect.TypeOf(config).Kind().String()
+	k = "null"
+	if config != nil {
+		k = reflect.TypeOf(config).Kind().String()
+	}
 	if k != "map" {
 		err = fmt.Errorf("contains type %q, must be a JSON element [%s]", k, filename)
 		return
@@ -58,13 +114,15 @@ func ReadConfigFile(data interface{}, filename string) (err error) {
 	}
 	parsed := Parsed{
 		FileName:     filename,
-		DistinctName: filepath.Base(filename),
+		DistinctName: fsys.base(filename),
 		ElementMap:   config.(map[string]interface{}),
+		Lines:        doc.Lines[0],
 	}
 
 	// store in resultMap
 	parsedMap := make(ParsedMap)
 	parsedMap["default"] = []Parsed{parsed}
+	renameDeprecated(st, parsedMap)
 
 	// Parse dataMap entries into data object (st, sv) fields
 	parseConfig(st, sv, parsedMap, &errList)
@@ -72,7 +130,7 @@ func ReadConfigFile(data interface{}, filename string) (err error) {
 	// Compile error list into an error message
 	if len(errList) > 0 {
 		if len(errList) == 1 {
-			err = fmt.Errorf(errList[0])
+			err = fmt.Errorf("%s", errList[0])
 		} else {
 			for i = range errList {
 				errList[i] = fmt.Sprintf("(#%d) %s", i+1, errList[i])
@@ -86,22 +144,63 @@ func ReadConfigFile(data interface{}, filename string) (err error) {
 // Read a list of config files into a map of structs, where 'data' points to struct and idName is field for map key
 // - Can configure application settings using one or more JSON files
 // - For example, put general settings in one file, credentials in a second file.
+// - A filename of "-" reads standard input, see ReadConfigSources() for other readers
 func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resultMap ResultMap, err error) {
-	var b []byte
+	fsys, filenames := stdinFileSystem(filenames)
+	return readConfigFiles("ReadConfigFiles", fsys, data, idName, [][]string{filenames})
+}
+
+// Read a list of config files from fsys, such as an embed.FS or fstest.MapFS, into a map of structs
+// - Filenames are relative to the root of fsys, and may include directories, such as "devices/fan.json"
+func ReadConfigFilesFS(fsys fs.FS, data interface{}, idName string, filenames ...string) (resultMap ResultMap, err error) {
+	return readConfigFiles("ReadConfigFilesFS", fsFileSystem{fsys: fsys}, data, idName, [][]string{filenames})
+}
+
+// Read an ordered list of config layers into a map of structs, where 'data' points to struct and idName is field for map key
+// - Each layer is a directory, listed as by ReadConfigDir() with default DirOptions, or a single file
+// - For example, layers config/base, config/prod and config/prod/host-42
+// - Settings in later layers override earlier ones, conflicts are only reported within a layer
+func ReadConfigLayers(data interface{}, idName string, layers ...string) (resultMap ResultMap, err error) {
+	var layerFiles [][]string
+	var filenames []string
+	var dirInfo os.FileInfo
+	var layer string
+
+	fsys := withArchives(osFileSystem{})
+	for _, layer = range layers {
+		dirInfo, err = fsys.stat(layer)
+		if err == nil && dirInfo.IsDir() {
+			if filenames, err = listDir(fsys, layer, DirOptions{}); err != nil {
+				return
+			}
+		} else {
+			// Not a directory, so DistinctFilenames() will validate it as a file
+			filenames = []string{layer}
+		}
+		layerFiles = append(layerFiles, filenames)
+	}
+	err = nil
+
+	return readConfigFiles("ReadConfigLayers", fsys, data, idName, layerFiles)
+}
+
+// Read config files, listed by layer, into a map of structs
+func readConfigFiles(caller string, fsys fileSystem, data interface{}, idName string, layers [][]string) (resultMap ResultMap, err error) {
 	var errList []string
-	var config, v interface{}
+	var filenames []string
 	var file FileDetail
-	var fileDetails []FileDetail
+	var fileDetails, overlays []FileDetail
+	var includeMap map[string][]string
 	var parsedMap ParsedMap
 	var parsedArr []Parsed
-	var k, elementId, idTag string
-	var i int
-	var ok, tag bool
+	var k, idTag, fullpath, filename string
+	var i, layer, idIndex int
+	var ok bool
 
 	// Make sure data is a pointer to a struct
 	k = reflect.TypeOf(data).Kind().String()
 	if k != "ptr" {
-		err = fmt.Errorf("ReadConfigFiles: 'data' must be ptr, not %s", k)
+		err = fmt.Errorf("%s: 'data' must be ptr, not %s", caller, k)
 		panic(err)
 	}
 	st := reflect.TypeOf(data).Elem()
@@ -111,127 +210,201 @@ func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resu
 	for i = 0; i < st.NumField(); i++ {
 		field := st.Field(i)
 		if field.Name == idName {
-			idTag, tag = field.Tag.Lookup("json")
+			idTag, _ = jsonName(field)
+			idIndex = i
 			ok = true
 			break
 		}
 	}
+	fields := newFieldKeys(st)
 	if !ok {
-		err = fmt.Errorf("ReadConfigFiles: 'data' does not contain field %s", idName)
+		err = fmt.Errorf("%s: 'data' does not contain field %s", caller, idName)
 		panic(err)
 	}
+	idKey := idName
+	if len(idTag) > 0 {
+		idKey = idTag
+	}
 
-	// Validate file list
-	fileDetails = DistinctFilenames(filenames, &errList)
+	// Gzip files and archive members are read like any other file
+	fsys = withArchives(fsys)
+
+	// Note the layer for each file, the first layer listed if a file is repeated
+	layerMap := make(map[string]int)
+	for layer, filenames = range layers {
+		for _, filename = range filenames {
+			fullpath, err = fsys.abs(filename)
+			if err != nil {
+				continue
+			}
+			if _, ok = layerMap[fullpath]; !ok {
+				layerMap[fullpath] = layer
+			}
+		}
+	}
+	err = nil
 
 	// Each file can contain a single element of type 'data', or an array of these elements
+	// - Elements go straight into parsedMap as they are decoded, and include directives are followed after each file
 	parsedMap = make(ParsedMap)
-	for _, file = range fileDetails {
-		if b, err = ioutil.ReadFile(file.Name); err != nil {
-			errList = append(errList, fmt.Sprintf("reading file: %v", err))
-			continue
+	addElement := func(file FileDetail, element Element) (elementId string, added bool) {
+		parsed := Parsed{
+			FileName:     file.Name,
+			DistinctName: file.DistinctName,
+			Position:     element.Position,
+			Layer:        layerMap[file.FullPath],
+			Lines:        element.Lines,
+			Untyped:      element.Untyped,
+		}
+		filename := file.Name
+		if parsed.Position > 0 {
+			filename = fmt.Sprintf("%s:elem#%d", file.Name, parsed.Position)
 		}
-		config = nil
 
-		// Parse config file as map[string]interface{}, or slice of these
-		err = json.Unmarshal(b, &config)
-		if err != nil {
-			if Debug {
-				log.Printf("Parsing issue, skipping [%s]", file.Name)
-			}
-			errList = append(errList, fmt.Sprintf("%v, skipping [%s]", err, file.Name))
-			continue
+		elementMap, isMap := element.Value.(map[string]interface{})
+		if !isMap {
+			errList = append(errList, fmt.Sprintf("element is not a JSON object, skipping [%s]", filename))
+			return
 		}
 
-		k = reflect.TypeOf(config).Kind().String()
-		if k == "map" {
-			if Debug {
-				log.Printf("Parsing single element [%s]", file.Name)
-			}
-			parsed := Parsed{
-				FileName:     file.Name,
-				DistinctName: file.DistinctName,
-				ElementMap:   config.(map[string]interface{}),
+		// Include directives are not settings, so an entry with only an include is not an element
+		if _, ok := elementMap[IncludeKey]; ok {
+			delete(elementMap, IncludeKey)
+			if len(elementMap) == 0 {
+				return
 			}
+		}
+		parsed.ElementMap = elementMap
 
-			// Find element Id by json tag or field name
-			v = nil
-			if tag {
-				v, _ = parsed.ElementMap[idTag]
-			}
-			if v == nil {
-				v, _ = parsed.ElementMap[idName]
-			}
-			if v == nil {
-				errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s]",
-					idName, file.Name))
-				continue
+		// Formats that name elements set the Id, unless the element sets it
+		if len(element.Id) > 0 && len(fields.elementValues(elementMap)[idIndex]) == 0 {
+			elementMap[idKey] = element.Id
+		}
+
+		// Find element Id by json tag, field name or alias
+		var v interface{}
+		if values := fields.elementValues(parsed.ElementMap)[idIndex]; len(values) > 0 {
+			v = values[0].value
+		}
+		if v == nil {
+			errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s]",
+				idName, filename))
+			return
+		}
+		elementId = fmt.Sprintf("%v", v)
+
+		// Add parsed to array and store in resultMap
+		parsedMap[elementId] = append(parsedMap[elementId], parsed)
+		return elementId, true
+	}
+
+	readFile := func(file FileDetail) (patterns []string, ok bool) {
+		var elementIds []string
+		var includeErr error
+		var count int
+
+		// Overlays are applied after all other files are read
+		if isOverlay(file.Name) {
+			overlays = append(overlays, file)
+			return nil, true
+		}
+
+		// A file with an error is skipped as a whole, so note what it added
+		errCount := len(errList)
+		err := decodeElements(fsys, file.Name, func(element Element) {
+			count++
+			elementPatterns, patternErr := includePatterns(element.Value)
+			if patternErr != nil && includeErr == nil {
+				includeErr = patternErr
 			}
-			elementId = fmt.Sprintf("%v", v)
-
-			// Add parsed to array and store in resultMap
-			parsedArr, ok = parsedMap[elementId]
-			if ok {
-				parsedArr = append(parsedArr, parsed)
-			} else {
-				parsedArr = []Parsed{parsed}
+			patterns = append(patterns, elementPatterns...)
+			if elementId, added := addElement(file, element); added {
+				elementIds = append(elementIds, elementId)
 			}
-			parsedMap[elementId] = parsedArr
-
-		} else if k == "slice" {
+		})
+		if err != nil {
 			if Debug {
-				log.Printf("Parsing %d elements [%s]", len(config.([]interface{})), file.Name)
+				log.Printf("Parsing issue, skipping [%s]", file.Name)
 			}
-			// Config file contains an array of elements of type 'data'
-			for i, v = range config.([]interface{}) {
-
-				parsed := Parsed{
-					FileName:     file.Name,
-					DistinctName: file.DistinctName,
-					Position:     i + 1,
-					ElementMap:   v.(map[string]interface{}),
+			for i := len(elementIds) - 1; i >= 0; i-- {
+				parsedArr := parsedMap[elementIds[i]]
+				if len(parsedArr) == 1 {
+					delete(parsedMap, elementIds[i])
+				} else {
+					parsedMap[elementIds[i]] = parsedArr[:len(parsedArr)-1]
 				}
+			}
+			errList = append(errList[:errCount], err.Error())
+			return nil, false
+		}
+		if Debug {
+			log.Printf("Parsed %d elements [%s]", count, file.Name)
+		}
+		if includeErr != nil {
+			errList = append(errList, fmt.Sprintf("%v [%s]", includeErr, file.Name))
+			return nil, true
+		}
+		return patterns, true
+	}
 
-				// Find element Id by json tag or field name
-				v = nil
-				if tag {
-					v, _ = parsed.ElementMap[idTag]
-				}
-				if v == nil {
-					v, _ = parsed.ElementMap[idName]
-				}
-				if v == nil {
-					errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s:elem#%d]",
-						idName, file.Name, parsed.Position))
-					continue
-				}
-				elementId = fmt.Sprintf("%v", v)
+	filenames = nil
+	for layer = range layers {
+		filenames = append(filenames, layers[layer]...)
+	}
+	fileDetails, includeMap = readIncludes(fsys, filenames, layerMap, &errList, readFile)
 
-				// Add parsed to array and store in resultMap
-				parsedArr, ok = parsedMap[elementId]
-				if ok {
-					parsedArr = append(parsedArr, parsed)
-				} else {
-					parsedArr = []Parsed{parsed}
-				}
-				parsedMap[elementId] = parsedArr
+	// Names are distinct once every file is read, including the chain of including files
+	detailMap := make(map[string]FileDetail)
+	for _, file = range fileDetails {
+		detailMap[file.Name] = file
+		detailMap[file.FullPath] = file
+	}
+	for _, parsedArr = range parsedMap {
+		for i = range parsedArr {
+			file = detailMap[parsedArr[i].FileName]
+			parsedArr[i].DistinctName = file.DistinctName
+			for _, fullpath = range includeMap[file.FullPath] {
+				parsedArr[i].IncludedFrom = append(parsedArr[i].IncludedFrom, detailMap[fullpath].DistinctName)
 			}
-		} else {
-			errList = append(errList, fmt.Sprintf("parsing config: unrecognized JSON type %q [%s]", k, file.Name))
-			continue
 		}
 	}
+	for i = range overlays {
+		overlays[i] = detailMap[overlays[i].Name]
+	}
+
+	// Order each element by layer, so later layers override earlier ones
+	for _, parsedArr = range parsedMap {
+		sort.SliceStable(parsedArr, func(i, j int) bool {
+			return parsedArr[i].Layer < parsedArr[j].Layer
+		})
+	}
+
+	// Optionally move deprecated settings to their replacement, as read from files
+	renameDeprecated(st, parsedMap)
+
+	// Apply overlays in layer order, then as named, each overriding everything before it
+	sort.SliceStable(overlays, func(i, j int) bool {
+		if layerMap[overlays[i].FullPath] != layerMap[overlays[j].FullPath] {
+			return layerMap[overlays[i].FullPath] < layerMap[overlays[j].FullPath]
+		}
+		return overlays[i].Name < overlays[j].Name
+	})
+	applyOverlays(fsys, parsedMap, overlays, len(layers), idName, idTag, &errList)
 
 	// check for conflicting values and unused parameters
 	validateParameters(st, parsedMap, &errList)
 
+	// Elements inherit settings from the element they extend, then from the wildcard Id
+	resolveExtends(parsedMap, idName, idTag, &errList)
+	inheritWildcard(parsedMap, idName, idTag)
+
 	// collapse each element into a single data object and load into resultMap
 	resultMap = parseConfig(st, sv, parsedMap, &errList)
 
 	// Compile error list into an error message
 	if len(errList) > 0 {
 		if len(errList) == 1 {
-			err = fmt.Errorf(errList[0])
+			err = fmt.Errorf("%s", errList[0])
 		} else {
 			for i = range errList {
 				errList[i] = fmt.Sprintf("(#%d) %s", i+1, errList[i])
@@ -0,0 +1,114 @@
+package json_configs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Write files under dir, by slash-separated name
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type layerDevice struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func TestReadConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/fan.json":          `{"name": "Fan", "host": "10.0.0.1", "port": 1}`,
+		"base/lamp.yaml":         "name: Lamp\nport: 2\n",
+		"prod/fan.json":          `{"name": "Fan", "port": 10}`,
+		"prod/host-42/fan.json":  `{"name": "Fan", "host": "10.0.0.42"}`,
+		"override.json":          `[{"name": "Lamp", "port": 20}, {"name": "Pump", "port": 30}]`,
+		"conflict/fan.json":      `{"name": "Fan", "port": 11}`,
+		"conflict/fan-copy.json": `{"name": "Fan", "port": 12}`,
+		"empty/notes.txt":        "not a config",
+		"prod/host-42/notes.txt": "not a config",
+	})
+	layer := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name   string
+		layers []string
+		result ResultMap
+		errs   []string // each error contains the string
+	}{
+		{
+			name:   "later layers override",
+			layers: []string{layer("base"), layer("prod"), layer("prod/host-42")},
+			result: ResultMap{
+				"Fan":  layerDevice{Name: "Fan", Host: "10.0.0.42", Port: 10},
+				"Lamp": layerDevice{Name: "Lamp", Port: 2},
+			},
+		},
+		{
+			name:   "file layer",
+			layers: []string{layer("base"), layer("override.json")},
+			result: ResultMap{
+				"Fan":  layerDevice{Name: "Fan", Host: "10.0.0.1", Port: 1},
+				"Lamp": layerDevice{Name: "Lamp", Port: 20},
+				"Pump": layerDevice{Name: "Pump", Port: 30},
+			},
+		},
+		{
+			name:   "later layer wins a conflict in an earlier layer",
+			layers: []string{layer("conflict"), layer("prod")},
+			result: ResultMap{"Fan": layerDevice{Name: "Fan", Port: 10}},
+			errs:   []string{"settings for Fan conflict, parameter Port"},
+		},
+		{
+			name:   "conflict within a layer",
+			layers: []string{layer("base"), layer("conflict")},
+			errs:   []string{"settings for Fan conflict, parameter Port"},
+		},
+		{
+			name:   "missing layer",
+			layers: []string{layer("base"), layer("missing")},
+			errs:   []string{"invalid, no such file"},
+		},
+		{
+			name:   "layer without config files",
+			layers: []string{layer("base"), layer("empty")},
+			errs:   []string{"no config files found in directory"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var device layerDevice
+			resultMap, err := ReadConfigLayers(&device, "Name", test.layers...)
+			var errs []string
+			if err != nil {
+				errs = strings.Split(err.Error(), "\n")
+			}
+			if len(errs) != len(test.errs) {
+				t.Fatalf("errors:\n got  %q\n want %q", errs, test.errs)
+			}
+			for i := range errs {
+				if !strings.Contains(errs[i], test.errs[i]) {
+					t.Errorf("errors:\n got  %q\n want %q", errs, test.errs)
+				}
+			}
+			if test.result != nil && !reflect.DeepEqual(resultMap, test.result) {
+				t.Errorf("got %+v, want %+v", resultMap, test.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package json_configs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// A named source of config, such as a pipe or in-memory buffer
+// - Name is used for distinct naming and error messages, and relative includes are resolved from it
+// - A Source with a nil Reader is the file Name
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Filename for standard input, and the name it is reported as
+const (
+	StdinFilename = "-"
+	StdinName     = "<stdin>"
+)
+
+// Read a list of config sources into a map of structs, where 'data' points to struct and idName is field for map key
+// - For example, Source{Name: "generated.json", Reader: cmd.Stdout} with files on disk
+func ReadConfigSources(data interface{}, idName string, sources ...Source) (resultMap ResultMap, err error) {
+	fsys, filenames := newSourceFileSystem(sources)
+	return readConfigFiles("ReadConfigSources", fsys, data, idName, [][]string{filenames})
+}
+
+// Sources read into memory, over the OS filesystem for files and includes
+// - Each source is read once, as files can be read more than once, such as for includes
+type sourceFileSystem struct {
+	osFileSystem
+	contentMap map[string]sourceContent
+}
+
+type sourceContent struct {
+	b   []byte
+	err error
+}
+
+// Read sources into memory, returning the filename of each
+// - A read error is reported when the source is read as a file
+func newSourceFileSystem(sources []Source) (fsys sourceFileSystem, filenames []string) {
+	var source Source
+	var b []byte
+	var err error
+	var ok bool
+
+	fsys.contentMap = make(map[string]sourceContent)
+	for _, source = range sources {
+		filenames = append(filenames, source.Name)
+		if source.Reader == nil {
+			continue
+		}
+		// A repeated name keeps the first source, DistinctFilenames() reports it as a duplicate
+		if _, ok = fsys.contentMap[source.Name]; ok {
+			continue
+		}
+		b, err = ioutil.ReadAll(source.Reader)
+		if err != nil {
+			err = fmt.Errorf("read %s: %v", source.Name, err)
+		}
+		fsys.contentMap[source.Name] = sourceContent{b: b, err: err}
+	}
+	return
+}
+
+// Use standard input for a filename of "-", otherwise the OS filesystem
+func stdinFileSystem(filenames []string) (fsys fileSystem, names []string) {
+	var sources []Source
+	var filename string
+	var stdin bool
+
+	for _, filename = range filenames {
+		if filename == StdinFilename {
+			sources = append(sources, Source{Name: StdinName, Reader: os.Stdin})
+			stdin = true
+		} else {
+			sources = append(sources, Source{Name: filename})
+		}
+	}
+	if !stdin {
+		return osFileSystem{}, filenames
+	}
+	return newSourceFileSystem(sources)
+}
+
+// A source's full path is its name, so it is distinct from any file
+func (f sourceFileSystem) abs(name string) (string, error) {
+	if _, ok := f.contentMap[name]; ok {
+		return name, nil
+	}
+	return f.osFileSystem.abs(name)
+}
+
+// A URL source is not split, so its distinct name is the URL
+func (f sourceFileSystem) split(name string) (dir, file string) {
+	if _, ok := f.contentMap[name]; ok && isURL(name) {
+		return "", name
+	}
+	return f.osFileSystem.split(name)
+}
+
+func (f sourceFileSystem) stat(name string) (fs.FileInfo, error) {
+	if content, ok := f.contentMap[name]; ok {
+		return sourceInfo{name: name, size: int64(len(content.b))}, nil
+	}
+	return f.osFileSystem.stat(name)
+}
+
+func (f sourceFileSystem) readFile(name string) ([]byte, error) {
+	if content, ok := f.contentMap[name]; ok {
+		return content.b, content.err
+	}
+	return f.osFileSystem.readFile(name)
+}
+
+func (f sourceFileSystem) open(name string) (io.ReadCloser, error) {
+	if content, ok := f.contentMap[name]; ok {
+		return ioutil.NopCloser(bytes.NewReader(content.b)), content.err
+	}
+	return f.osFileSystem.open(name)
+}
+
+// File info for a source, which is never a directory
+type sourceInfo struct {
+	name string
+	size int64
+}
+
+func (s sourceInfo) Name() string       { return s.name }
+func (s sourceInfo) Size() int64        { return s.size }
+func (s sourceInfo) Mode() fs.FileMode  { return 0444 }
+func (s sourceInfo) ModTime() time.Time { return time.Time{} }
+func (s sourceInfo) IsDir() bool        { return false }
+func (s sourceInfo) Sys() interface{}   { return nil }
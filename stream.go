@@ -0,0 +1,155 @@
+package json_configs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Count lines read, for the line of each element in a stream
+type lineCounter struct {
+	r        io.Reader
+	newlines int
+}
+
+func (c *lineCounter) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.newlines += bytes.Count(p[:n], []byte("\n"))
+	return
+}
+
+// Count newlines written
+type newlineCounter int
+
+func (c *newlineCounter) Write(p []byte) (n int, err error) {
+	*c += newlineCounter(bytes.Count(p, []byte("\n")))
+	return len(p), nil
+}
+
+// Decode a JSON array one element at a time, other JSON content at once
+// - A lenient file is converted as a whole
+func (d JSONDecoder) DecodeStream(r io.Reader, emit func(element Element)) (err error) {
+	var b, raw []byte
+	var doc Document
+	var tok json.Token
+	var v interface{}
+	var start, end, skipped int
+
+	br := bufio.NewReader(r)
+	if !d.Lenient && !LenientJSON {
+		// Peek past whitespace for the start of an array
+		for {
+			if b, err = br.Peek(1); err != nil || (b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n') {
+				break
+			}
+			if b[0] == '\n' {
+				skipped++
+			}
+			br.ReadByte()
+		}
+	}
+	if d.Lenient || LenientJSON || err != nil || b[0] != '[' {
+		if b, err = ioutil.ReadAll(br); err != nil {
+			return
+		}
+		// Newlines skipped while peeking are put back, so key lines are in the file
+		b = append(bytes.Repeat([]byte("\n"), skipped), b...)
+		if doc, err = d.Decode(b); err != nil {
+			return
+		}
+		return doc.emit(emit)
+	}
+
+	counter := &lineCounter{r: br, newlines: skipped}
+	dec := json.NewDecoder(counter)
+
+	// Line at the decoder's offset, from lines read less those still buffered
+	lineAt := func() int {
+		var buffered newlineCounter
+		io.Copy(&buffered, dec.Buffered())
+		return 1 + counter.newlines - int(buffered)
+	}
+
+	if _, err = dec.Token(); err != nil {
+		return
+	}
+	for position := 1; dec.More(); position++ {
+		var element json.RawMessage
+		if err = dec.Decode(&element); err != nil {
+			return
+		}
+		raw = element
+		end = lineAt()
+		start = end - bytes.Count(raw, []byte("\n"))
+
+		v = nil
+		if err = json.Unmarshal(raw, &v); err != nil {
+			return
+		}
+		lines := keyLines(raw)[0]
+		for key := range lines {
+			lines[key] += start - 1
+		}
+		emit(Element{Position: position, Value: v, Lines: lines})
+	}
+	if _, err = dec.Token(); err != nil {
+		return
+	}
+	if tok, err = dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("invalid content after top-level value: %v", tok)
+		}
+		return
+	}
+	return nil
+}
+
+// JSONLinesDecoder parses JSON Lines (NDJSON) config files, where each line is an element
+// - Position is the line number, and blank lines are skipped
+// - Files are read a line at a time, see DecodeStream()
+type JSONLinesDecoder struct{}
+
+func (d JSONLinesDecoder) Decode(b []byte) (doc Document, err error) {
+	var elements []interface{}
+
+	err = d.DecodeStream(bytes.NewReader(b), func(element Element) {
+		elements = append(elements, element.Value)
+		doc.Positions = append(doc.Positions, element.Position)
+		for key, line := range element.Lines {
+			doc.setLine(element.Position, key, line)
+		}
+	})
+	doc.Config = elements
+	return
+}
+
+func (JSONLinesDecoder) DecodeStream(r io.Reader, emit func(element Element)) (err error) {
+	var b []byte
+	var v interface{}
+	var line int
+
+	br := bufio.NewReader(r)
+	for err == nil {
+		b, err = br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		line++
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		v = nil
+		if jsonErr := json.Unmarshal(b, &v); jsonErr != nil {
+			return fmt.Errorf("line %d: %v", line, jsonErr)
+		}
+		lines := keyLines(b)[0]
+		for key := range lines {
+			lines[key] = line
+		}
+		emit(Element{Position: line, Value: v, Lines: lines})
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package json_configs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Decode with DecodeStream, collecting the elements
+func decodeTestStream(decoder StreamDecoder, input string) (elements []Element, err error) {
+	err = decoder.DecodeStream(strings.NewReader(input), func(element Element) {
+		elements = append(elements, element)
+	})
+	return
+}
+
+func TestJSONDecodeStream(t *testing.T) {
+	// A large array, so elements are read across many buffer refills
+	var large strings.Builder
+	var largeLines []map[string]int
+	line := 1
+	large.WriteString("[")
+	for i := 0; i < 3000; i++ {
+		if i > 0 {
+			large.WriteString(",")
+		}
+		for j := 0; j < i%3; j++ {
+			large.WriteString("\n")
+			line++
+		}
+		large.WriteString(fmt.Sprintf("{\"name\": \"dev%d\",\n  \"note\": \"%s\"}", i, strings.Repeat("x", i%200)))
+		largeLines = append(largeLines, map[string]int{"name": line, "note": line + 1})
+		line++
+	}
+	large.WriteString("\n]\n")
+
+	tests := []struct {
+		name  string
+		input string
+		lines []map[string]int
+		err   string
+	}{
+		{
+			name:  "one element per line",
+			input: "[\n{\"name\": \"Fan\"},\n{\"name\": \"Lamp\"}\n]\n",
+			lines: []map[string]int{{"name": 2}, {"name": 3}},
+		},
+		{
+			name:  "leading blank lines",
+			input: "\n\n  \n[{\"name\": \"Fan\"},\n\n\n {\"name\": \"Lamp\"}]",
+			lines: []map[string]int{{"name": 4}, {"name": 7}},
+		},
+		{
+			name:  "elements on one line",
+			input: `[{"name": "Fan"}, {"name": "Lamp"}, {"name": "Pump"}]`,
+			lines: []map[string]int{{"name": 1}, {"name": 1}, {"name": 1}},
+		},
+		{
+			name:  "multi-line elements",
+			input: "[\n  {\n    \"name\": \"Fan\",\n    \"net\": {\n      \"host\": \"a\"\n    },\n    \"port\": 1\n  },\n  {\n    \"name\": \"Lamp\"\n  }\n]",
+			lines: []map[string]int{{"name": 3, "net": 4, "port": 7}, {"name": 10}},
+		},
+		{
+			name:  "CRLF and escaped newlines",
+			input: "[\r\n{\"name\": \"a\\nb\",\r\n\"port\": 1},\r\n{\"name\": \"Lamp\"}\r\n]\r\n",
+			lines: []map[string]int{{"name": 2, "port": 3}, {"name": 4}},
+		},
+		{
+			name:  "large array",
+			input: large.String(),
+			lines: largeLines,
+		},
+		{
+			name:  "content after the array",
+			input: `[{"name": "Fan"}] {}`,
+			err:   "invalid content after top-level value",
+		},
+		{
+			name:  "bad element",
+			input: "[{\"name\": \"Fan\"},\n{\"name\" \"Lamp\"}]",
+			err:   "invalid character",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			elements, err := decodeTestStream(JSONDecoder{}, test.input)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Lines and positions match the expected lines, and decoding the whole file
+			doc, err := JSONDecoder{}.Decode([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(elements) != len(test.lines) {
+				t.Fatalf("got %d elements, want %d", len(elements), len(test.lines))
+			}
+			for i, element := range elements {
+				if element.Position != i+1 {
+					t.Errorf("element %d: position %d", i+1, element.Position)
+				}
+				if !reflect.DeepEqual(element.Lines, test.lines[i]) || !reflect.DeepEqual(element.Lines, doc.Lines[i+1]) {
+					t.Fatalf("element %d: lines %v, want %v, decoded whole %v", i+1, element.Lines, test.lines[i], doc.Lines[i+1])
+				}
+				if !reflect.DeepEqual(element.Value, doc.Config.([]interface{})[i]) {
+					t.Errorf("element %d: got %v, want %v", i+1, element.Value, doc.Config.([]interface{})[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONDecodeStreamDocument(t *testing.T) {
+	// Content other than an array, or lenient JSON, is decoded at once
+	tests := []struct {
+		decoder JSONDecoder
+		input   string
+		lines   []map[string]int
+	}{
+		{JSONDecoder{}, "\n{\"name\": \"Fan\",\n\"port\": 1}", []map[string]int{{"name": 2, "port": 3}}},
+		{JSONDecoder{Lenient: true}, "[\n// fan\n{name: 'Fan'},\n]", []map[string]int{{"name": 3}}},
+	}
+	for _, test := range tests {
+		elements, err := decodeTestStream(test.decoder, test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		var lines []map[string]int
+		for _, element := range elements {
+			lines = append(lines, element.Lines)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%q: got lines %v, want %v", test.input, lines, test.lines)
+		}
+	}
+}
+
+func TestJSONLinesDecoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		positions []int
+		config    []interface{}
+		err       string
+	}{
+		{
+			name:      "lines",
+			input:     "{\"name\": \"Fan\"}\n\n  \n{\"name\": \"Lamp\", \"port\": 2}\r\n{\"name\": \"Pump\"}",
+			positions: []int{1, 4, 5},
+			config: []interface{}{
+				map[string]interface{}{"name": "Fan"},
+				map[string]interface{}{"name": "Lamp", "port": 2.0},
+				map[string]interface{}{"name": "Pump"},
+			},
+		},
+		{
+			name:  "bad line",
+			input: "{\"name\": \"Fan\"}\n\n{\"name\": }\n",
+			err:   "line 3: invalid character",
+		},
+		{
+			name:  "element over two lines",
+			input: "{\"name\":\n\"Fan\"}\n",
+			err:   "line 1: unexpected end of JSON input",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := JSONLinesDecoder{}.Decode([]byte(test.input))
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Positions, test.positions) || !reflect.DeepEqual(doc.Config, test.config) {
+				t.Errorf("got %v %v, want %v %v", doc.Positions, doc.Config, test.positions, test.config)
+			}
+			for _, position := range test.positions {
+				if doc.Lines[position]["name"] != position {
+					t.Errorf("line of name at position %d: got %d", position, doc.Lines[position]["name"])
+				}
+			}
+		})
+	}
+}
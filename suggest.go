@@ -0,0 +1,72 @@
+package json_configs
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Suggest the closest json tag or field name of data object (st) for an unused parameter
+// - A case-insensitive match is suggested first, then the smallest edit distance of 2 or less
+// - The suggestion is the json tag name if the field has one, as that is what files normally use
+// - Returns "" if nothing is close enough
+func suggestParam(st reflect.Type, paramName string) (suggestion string) {
+	var names []string
+	var name, tag string
+	var i, d int
+	var ok bool
+
+	best := 3
+	for i = 0; i < st.NumField(); i++ {
+		param := st.Field(i)
+		if tagOption(param, "remain") {
+			continue
+		}
+		names = []string{param.Name}
+		if tag, ok = jsonName(param); ok && tag != "-" {
+			names = []string{tag, param.Name}
+		}
+
+		for _, name = range names {
+			if strings.EqualFold(name, paramName) {
+				return names[0]
+			}
+			d = editDistance(strings.ToLower(paramName), strings.ToLower(name))
+			if d < best && d < len(paramName) {
+				best = d
+				suggestion = names[0]
+			}
+		}
+	}
+	return
+}
+
+// Levenshtein distance between strings a and b
+func editDistance(a, b string) int {
+	var i, j, cost int
+
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j = range prev {
+		prev[j] = j
+	}
+	for i = 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j = 1; j <= len(rb); j++ {
+			cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,163 @@
+package json_configs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Name from the json tag of field 'param', ignoring options such as omitempty
+// - Returns ok false if there is no tag, or the tag has no name
+func jsonName(param reflect.StructField) (name string, ok bool) {
+	tag, ok := param.Tag.Lookup("json")
+	if !ok {
+		return
+	}
+	name = strings.Split(tag, ",")[0]
+	ok = len(name) > 0
+	return
+}
+
+// Key normally used for field 'param', the json tag name, or the field name if there is no tag
+func keyName(param reflect.StructField) (name string) {
+	var ok bool
+
+	name, ok = jsonName(param)
+	if !ok {
+		name = param.Name
+	}
+	return
+}
+
+// Check for an option in the json tag of field 'param', such as `json:",remain"`
+func tagOption(param reflect.StructField, option string) bool {
+	tag, ok := param.Tag.Lookup("json")
+	if !ok {
+		return false
+	}
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Locate the field in data object (st) tagged `json:",remain"`, to capture keys not used by other fields
+// - Returns index -1 if there is no such field
+func remainField(st reflect.Type) (index int) {
+	var i int
+
+	index = -1
+	for i = 0; i < st.NumField(); i++ {
+		param := st.Field(i)
+		if !tagOption(param, "remain") {
+			continue
+		}
+		if param.Type != reflect.TypeOf(map[string]interface{}{}) {
+			panic(fmt.Errorf("field %s with remain option must be map[string]interface{}, not %s",
+				param.Name, param.Type))
+		}
+		index = i
+		break
+	}
+	return
+}
+
+// Keys that set each field of data object (st)
+// - A key is the json tag name, the field name, or an alias from an `aliases:"deviceId,device-id"` tag
+// - Aliases are old spellings, so using one emits a deprecation warning
+// - With CaseInsensitive set, keys also match ignoring case
+type fieldKeys struct {
+	exact map[string]fieldKey
+	lower map[string]fieldKey
+}
+
+// Field a key sets, and its rank: 0 for json tag name, 1 for field name, 2... for aliases in order
+type fieldKey struct {
+	index int
+	rank  int
+}
+
+// Setting for a field in an element, and the key it was found by
+type keyValue struct {
+	key   string
+	rank  int
+	exact bool
+	value interface{}
+}
+
+func newFieldKeys(st reflect.Type) (fields fieldKeys) {
+	var tag, alias string
+	var i, j int
+	var ok bool
+
+	fields.exact = make(map[string]fieldKey)
+	fields.lower = make(map[string]fieldKey)
+	add := func(key string, fk fieldKey) {
+		if _, ok := fields.exact[key]; !ok {
+			fields.exact[key] = fk
+		}
+		if _, ok := fields.lower[strings.ToLower(key)]; !ok {
+			fields.lower[strings.ToLower(key)] = fk
+		}
+	}
+
+	for i = 0; i < st.NumField(); i++ {
+		param := st.Field(i)
+		if tagOption(param, "remain") {
+			continue
+		}
+		if tag, ok = jsonName(param); ok {
+			add(tag, fieldKey{index: i, rank: 0})
+		}
+		add(param.Name, fieldKey{index: i, rank: 1})
+		for j, alias = range strings.Split(param.Tag.Get("aliases"), ",") {
+			if alias = strings.TrimSpace(alias); len(alias) > 0 {
+				add(alias, fieldKey{index: i, rank: 2 + j})
+			}
+		}
+	}
+	return
+}
+
+// Field set by a key, matching case-insensitively if CaseInsensitive is set
+func (fields fieldKeys) lookup(key string) (fk fieldKey, exact, ok bool) {
+	fk, ok = fields.exact[key]
+	if ok {
+		exact = true
+		return
+	}
+	if CaseInsensitive {
+		fk, ok = fields.lower[strings.ToLower(key)]
+	}
+	return
+}
+
+// Settings in an element for each field index, in order of precedence
+// - Json tag name first, then field name, then aliases, with exact matches before case-insensitive ones
+func (fields fieldKeys) elementValues(elementMap ElementMap) (values map[int][]keyValue) {
+	var key string
+	var v interface{}
+
+	values = make(map[int][]keyValue)
+	for key, v = range elementMap {
+		fk, exact, ok := fields.lookup(key)
+		if ok {
+			values[fk.index] = append(values[fk.index], keyValue{key: key, rank: fk.rank, exact: exact, value: v})
+		}
+	}
+	for _, kvs := range values {
+		sort.Slice(kvs, func(i, j int) bool {
+			if kvs[i].rank != kvs[j].rank {
+				return kvs[i].rank < kvs[j].rank
+			}
+			if kvs[i].exact != kvs[j].exact {
+				return kvs[i].exact
+			}
+			return kvs[i].key < kvs[j].key
+		})
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package json_configs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts accepted for Time fields, in order, unless the field has a `layout:"..."` tag
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Time zone for times without one, unless the field has a `tz:"America/New_York"` tag
+var TimeZone = time.UTC
+
+// Parse a time using the layout tag or TimeLayouts, then as a Unix epoch number
+// - Epoch numbers are in seconds, or the unit from a `unit:"ms"` tag
+func parseTime(tag reflect.StructTag, s string) (date time.Time, err error) {
+	var layouts []string
+	var layout, unit string
+	var unitDur time.Duration
+	var f float64
+	var ok bool
+
+	loc := TimeZone
+	if tz, hasTZ := tag.Lookup("tz"); hasTZ {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			err = fmt.Errorf("time zone %s", tz)
+			return
+		}
+	}
+
+	layouts = TimeLayouts
+	if layout, ok = tag.Lookup("layout"); ok {
+		layouts = []string{layout}
+	}
+	s = strings.TrimSpace(s)
+	for _, layout = range layouts {
+		if date, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return
+		}
+	}
+
+	// Unix epoch, in seconds unless the unit tag says otherwise
+	if f, err = strconv.ParseFloat(s, 64); err != nil {
+		err = fmt.Errorf("date %s", s)
+		return
+	}
+	if unit, ok = tag.Lookup("unit"); !ok {
+		unit = "s"
+	}
+	if unitDur, err = time.ParseDuration("1" + unit); err != nil {
+		err = fmt.Errorf("date %s, invalid unit %q", s, unit)
+		return
+	}
+	whole := math.Trunc(f)
+	date = time.Unix(0, 0).Add(time.Duration(whole)*unitDur + time.Duration((f-whole)*float64(unitDur)))
+	date = date.In(loc)
+	return
+}
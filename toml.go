@@ -0,0 +1,554 @@
+package json_configs
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TOMLDecoder parses TOML config files, as in TOML v1.0
+// - A document is a single element
+// - A document of only one array of tables, such as [[device]] entries, is an array of elements
+// - Dates and times are strings, for Time fields to parse
+type TOMLDecoder struct{}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+
+	// Tables defined by a [header], and tables that are fixed by an inline table or array
+	defined map[string]bool
+	fixed   map[string]bool
+
+	// Lines of root keys, and of the keys in each top level array of tables
+	rootLines  map[string]int
+	arrayLines map[string][]map[string]int
+}
+
+var (
+	tomlDateTimeRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?([Zz]|[-+]\d{2}:\d{2})?)?|^\d{2}:\d{2}:\d{2}(\.\d+)?`)
+	tomlNumberRegexp   = regexp.MustCompile(`^[-+]?(0x[0-9a-fA-F_]+|0o[0-7_]+|0b[01_]+|inf|nan|[0-9_]+(\.[0-9_]+)?([eE][-+]?[0-9_]+)?)`)
+)
+
+func (TOMLDecoder) Decode(b []byte) (doc Document, err error) {
+	var key string
+	var keys []string
+	var table map[string]interface{}
+	var tableKeys []string
+	var tableLines map[string]int
+	var v interface{}
+	var array bool
+
+	p := &tomlParser{
+		s:          strings.Replace(strings.TrimPrefix(string(b), "\ufeff"), "\r\n", "\n", -1),
+		line:       1,
+		defined:    make(map[string]bool),
+		fixed:      make(map[string]bool),
+		rootLines:  make(map[string]int),
+		arrayLines: make(map[string][]map[string]int),
+	}
+	root := make(map[string]interface{})
+	table = root
+	tableLines = p.rootLines
+
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			break
+		}
+
+		if p.s[p.pos] == '[' {
+			// Table header [a.b], or array of tables [[a.b]]
+			line := p.line
+			array = strings.HasPrefix(p.s[p.pos:], "[[")
+			if array {
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			if tableKeys, err = p.parseKey(); err != nil {
+				return
+			}
+			if array && !strings.HasPrefix(p.s[p.pos:], "]]") || !array && !strings.HasPrefix(p.s[p.pos:], "]") {
+				err = p.errorf("expected ] after table name")
+				return
+			}
+			if array {
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			if table, err = p.openTable(root, tableKeys, array); err != nil {
+				return
+			}
+			if _, ok := p.rootLines[tableKeys[0]]; !ok {
+				p.rootLines[tableKeys[0]] = line
+			}
+			tableLines = nil
+			if array && len(tableKeys) == 1 {
+				tableLines = make(map[string]int)
+				p.arrayLines[tableKeys[0]] = append(p.arrayLines[tableKeys[0]], tableLines)
+			}
+		} else {
+			// Key/value pair, where a dotted key creates tables
+			line := p.line
+			if keys, err = p.parseKey(); err != nil {
+				return
+			}
+			p.skipSpace(false)
+			if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+				err = p.errorf("expected = after key %s", strings.Join(keys, "."))
+				return
+			}
+			p.pos++
+			p.skipSpace(false)
+			if v, err = p.parseValue(); err != nil {
+				return
+			}
+			if err = p.setKey(table, keys, v, tableKeys); err != nil {
+				return
+			}
+			if tableLines != nil {
+				if _, ok := tableLines[keys[0]]; !ok {
+					tableLines[keys[0]] = line
+				}
+			}
+		}
+
+		// Only a comment can follow on the line
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] != '\n' {
+			err = p.errorf("unexpected %q at end of line", p.s[p.pos])
+			return
+		}
+	}
+
+	// A document of only one array of tables is an array of elements
+	if len(root) == 1 {
+		for key, v = range root {
+			if elements, ok := v.([]interface{}); ok && len(p.arrayLines[key]) == len(elements) && len(elements) > 0 {
+				doc.Config = elements
+				for i, lines := range p.arrayLines[key] {
+					for k, line := range lines {
+						doc.setLine(i+1, k, line)
+					}
+				}
+				return
+			}
+		}
+	}
+	doc.Config = root
+	for key = range p.rootLines {
+		doc.setLine(0, key, p.rootLines[key])
+	}
+	return
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// Skip spaces and comments, and newlines if 'newlines'
+func (p *tomlParser) skipSpace(newlines bool) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Parse a bare, quoted or dotted key
+func (p *tomlParser) parseKey() (keys []string, err error) {
+	var key string
+
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			err = p.errorf("expected a key")
+			return
+		}
+		switch c := p.s[p.pos]; {
+		case c == '"' || c == '\'':
+			if key, err = p.parseString(); err != nil {
+				return
+			}
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && tomlBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				err = p.errorf("invalid key character %q", c)
+				return
+			}
+			key = p.s[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return
+	}
+}
+
+func tomlBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// Open the table for a [header], creating tables along the path
+func (p *tomlParser) openTable(root map[string]interface{}, keys []string, array bool) (table map[string]interface{}, err error) {
+	var i int
+
+	path := strings.Join(keys, "\x00")
+	if p.fixed[path] {
+		err = p.errorf("table %s is already defined inline", strings.Join(keys, "."))
+		return
+	}
+
+	table = root
+	for i = 0; i < len(keys)-1; i++ {
+		if table, err = p.childTable(table, keys[:i+1]); err != nil {
+			return
+		}
+	}
+	key := keys[len(keys)-1]
+
+	if array {
+		v, ok := table[key]
+		if !ok {
+			v = []interface{}{}
+		}
+		arr, isArray := v.([]interface{})
+		if !isArray || p.fixed[path] {
+			err = p.errorf("key %s is not an array of tables", strings.Join(keys, "."))
+			return
+		}
+		// Sub-tables of the previous entry can be defined again in the new one
+		for defined := range p.defined {
+			if strings.HasPrefix(defined, path+"\x00") {
+				delete(p.defined, defined)
+			}
+		}
+		child := make(map[string]interface{})
+		table[key] = append(arr, child)
+		return child, nil
+	}
+
+	if p.defined[path] {
+		err = p.errorf("table %s is defined twice", strings.Join(keys, "."))
+		return
+	}
+	p.defined[path] = true
+	return p.childTable(table, keys)
+}
+
+// The child table for the last of 'keys', or the last table of an array of tables
+func (p *tomlParser) childTable(table map[string]interface{}, keys []string) (child map[string]interface{}, err error) {
+	key := keys[len(keys)-1]
+	v, ok := table[key]
+	if !ok {
+		child = make(map[string]interface{})
+		table[key] = child
+		return
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if p.fixed[strings.Join(keys, "\x00")] {
+			err = p.errorf("table %s is already defined inline", strings.Join(keys, "."))
+			return
+		}
+		return t, nil
+	case []interface{}:
+		if len(t) > 0 {
+			if child, ok = t[len(t)-1].(map[string]interface{}); ok && !p.fixed[strings.Join(keys, "\x00")] {
+				return
+			}
+		}
+	}
+	err = p.errorf("key %s is already set", strings.Join(keys, "."))
+	return
+}
+
+// Set a dotted key in 'table', which is at 'tableKeys' from the root
+func (p *tomlParser) setKey(table map[string]interface{}, keys []string, v interface{}, tableKeys []string) (err error) {
+	var i int
+
+	fullKeys := append(tableKeys[:len(tableKeys):len(tableKeys)], keys...)
+	for i = 0; i < len(keys)-1; i++ {
+		if table, err = p.childTable(table, fullKeys[:len(tableKeys)+i+1]); err != nil {
+			return
+		}
+	}
+	key := keys[len(keys)-1]
+	if _, ok := table[key]; ok {
+		return p.errorf("duplicate key %s", strings.Join(fullKeys, "."))
+	}
+	table[key] = v
+
+	// Inline tables and arrays can't be extended later
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		p.fixed[strings.Join(fullKeys, "\x00")] = true
+	}
+	return
+}
+
+// Parse a value: string, number, boolean, date/time, array or inline table
+func (p *tomlParser) parseValue() (v interface{}, err error) {
+	var s string
+	var n int64
+	var f float64
+
+	if p.pos >= len(p.s) {
+		err = p.errorf("expected a value")
+		return
+	}
+	rest := p.s[p.pos:]
+
+	switch c := rest[0]; {
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case strings.HasPrefix(rest, "true") && (len(rest) == 4 || !tomlBareKeyChar(rest[4])):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false") && (len(rest) == 5 || !tomlBareKeyChar(rest[5])):
+		p.pos += 5
+		return false, nil
+	}
+
+	if s = tomlDateTimeRegexp.FindString(rest); len(s) > 0 {
+		p.pos += len(s)
+		return s, nil
+	}
+	if s = tomlNumberRegexp.FindString(rest); len(s) > 0 {
+		p.pos += len(s)
+		num := strings.Replace(s, "_", "", -1)
+		sign := 1.0
+		if strings.HasPrefix(num, "-") {
+			sign = -1
+		}
+		unsigned := strings.TrimLeft(num, "+-")
+		switch {
+		case unsigned == "inf":
+			return sign * math.Inf(1), nil
+		case unsigned == "nan":
+			return math.NaN(), nil
+		case strings.HasPrefix(unsigned, "0x"), strings.HasPrefix(unsigned, "0o"), strings.HasPrefix(unsigned, "0b"):
+			if n, err = strconv.ParseInt(unsigned[2:], map[byte]int{'x': 16, 'o': 8, 'b': 2}[unsigned[1]], 64); err != nil {
+				err = p.errorf("invalid number %s", s)
+				return
+			}
+			return sign * float64(n), nil
+		}
+		// A decimal integer part can't have leading zeros, such as 021
+		if digits := strings.IndexAny(unsigned+".", ".eE"); digits > 1 && unsigned[0] == '0' {
+			err = p.errorf("invalid number %s", s)
+			return
+		}
+		if f, err = strconv.ParseFloat(num, 64); err != nil {
+			err = p.errorf("invalid number %s", s)
+			return
+		}
+		return f, nil
+	}
+
+	end := strings.IndexAny(rest, " \t\n,]}#")
+	if end < 0 {
+		end = len(rest)
+	}
+	err = p.errorf("invalid value %s", rest[:end])
+	return
+}
+
+// Parse a basic, literal or multi-line string
+func (p *tomlParser) parseString() (s string, err error) {
+	var sb strings.Builder
+	var code uint64
+	var size int
+
+	quote := p.s[p.pos]
+	multi := strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3))
+	if multi {
+		p.pos += 3
+		// A newline right after the opening quotes is trimmed
+		if strings.HasPrefix(p.s[p.pos:], "\n") {
+			p.pos++
+			p.line++
+		}
+	} else {
+		p.pos++
+	}
+
+	for {
+		if p.pos >= len(p.s) {
+			err = p.errorf("unterminated string")
+			return
+		}
+		c := p.s[p.pos]
+		switch {
+		case multi && strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3)):
+			// Up to two more quotes can end the content
+			p.pos += 3
+			for i := 0; i < 2 && p.pos < len(p.s) && p.s[p.pos] == quote; i++ {
+				sb.WriteByte(quote)
+				p.pos++
+			}
+			return sb.String(), nil
+		case !multi && c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\n':
+			if !multi {
+				err = p.errorf("newline in string")
+				return
+			}
+			sb.WriteByte(c)
+			p.line++
+			p.pos++
+		case c == '\\' && quote == '"':
+			p.pos++
+			if p.pos >= len(p.s) {
+				continue
+			}
+			switch e := p.s[p.pos]; e {
+			case 'b':
+				sb.WriteByte('\b')
+			case 't':
+				sb.WriteByte('\t')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'e':
+				sb.WriteByte(0x1b)
+			case '"', '\\':
+				sb.WriteByte(e)
+			case 'u', 'U':
+				size = 4
+				if e == 'U' {
+					size = 8
+				}
+				if p.pos+size >= len(p.s) {
+					err = p.errorf("invalid escape \\%c", e)
+					return
+				}
+				if code, err = strconv.ParseUint(p.s[p.pos+1:p.pos+1+size], 16, 32); err != nil {
+					err = p.errorf("invalid escape \\%s", p.s[p.pos:p.pos+1+size])
+					return
+				}
+				sb.WriteRune(rune(code))
+				p.pos += size
+			default:
+				// A line ending backslash trims the newline and following whitespace
+				if multi && (e == ' ' || e == '\t' || e == '\n') {
+					for p.pos < len(p.s) && strings.IndexByte(" \t\n", p.s[p.pos]) >= 0 {
+						if p.s[p.pos] == '\n' {
+							p.line++
+						}
+						p.pos++
+					}
+					continue
+				}
+				err = p.errorf("invalid escape \\%c", e)
+				return
+			}
+			p.pos++
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// Parse an array, which can span lines
+func (p *tomlParser) parseArray() (arr []interface{}, err error) {
+	var v interface{}
+
+	arr = []interface{}{}
+	p.pos++
+	for {
+		p.skipSpace(true)
+		if p.pos < len(p.s) && p.s[p.pos] == ']' {
+			p.pos++
+			return
+		}
+		if v, err = p.parseValue(); err != nil {
+			return
+		}
+		arr = append(arr, v)
+		p.skipSpace(true)
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+			err = p.errorf("expected , or ] in array")
+			return
+		}
+	}
+}
+
+// Parse an inline table, such as {host = "10.0.0.1", port = 21000}
+func (p *tomlParser) parseInlineTable() (table map[string]interface{}, err error) {
+	var keys []string
+	var v interface{}
+
+	table = make(map[string]interface{})
+	saved := p.fixed
+	p.fixed = make(map[string]bool)
+	defer func() { p.fixed = saved }()
+
+	p.pos++
+	p.skipSpace(false)
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return
+	}
+	for {
+		if keys, err = p.parseKey(); err != nil {
+			return
+		}
+		if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+			err = p.errorf("expected = after key %s", strings.Join(keys, "."))
+			return
+		}
+		p.pos++
+		p.skipSpace(false)
+		if v, err = p.parseValue(); err != nil {
+			return
+		}
+		if err = p.setKey(table, keys, v, nil); err != nil {
+			return
+		}
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '}' {
+			p.pos++
+			return
+		}
+		err = p.errorf("expected , or } in inline table")
+		return
+	}
+}
@@ -0,0 +1,99 @@
+package json_configs
+
+import (
+	"testing"
+)
+
+func TestTOMLDecoder(t *testing.T) {
+	runDecoderTests(t, TOMLDecoder{}, []decoderTest{
+		{
+			name:  "values",
+			input: "# device\nname = \"Fan\" # trailing\nport = 21_000\nratio = 0.5\nenabled = true\nhex = 0x1F\nexp = 1e3\npath = 'C:\\temp'\ntags = [\"a\", \"b\",]\n",
+			config: map[string]interface{}{
+				"name": "Fan", "port": 21000.0, "ratio": 0.5, "enabled": true, "hex": 31.0, "exp": 1000.0,
+				"path": `C:\temp`, "tags": []interface{}{"a", "b"},
+			},
+			lines: map[int]map[string]int{0: {"name": 2, "port": 3, "ratio": 4, "enabled": 5, "hex": 6, "exp": 7, "path": 8, "tags": 9}},
+		},
+		{
+			name:  "strings",
+			input: "basic = \"tab\\tquote\\\" \\u00e9\"\nmulti = \"\"\"\nline 1\nline 2\\\n  joined\"\"\"\nliteral = '''\nraw \\n'''\n",
+			config: map[string]interface{}{
+				"basic": "tab\tquote\" \u00e9", "multi": "line 1\nline 2joined", "literal": "raw \\n",
+			},
+		},
+		{
+			name:  "dates stay strings",
+			input: "installed = 2024-01-02T03:04:05Z\nday = 2024-01-02\nlocal = 2024-01-02 03:04:05.5\nat = 07:30:00\n",
+			config: map[string]interface{}{
+				"installed": "2024-01-02T03:04:05Z", "day": "2024-01-02", "local": "2024-01-02 03:04:05.5", "at": "07:30:00",
+			},
+		},
+		{
+			name:  "dotted keys",
+			input: "name = \"Fan\"\nnet.host = \"10.0.0.1\"\nnet.port = 21000\n\"quoted.key\" = 1\n[limits]\nspeed.max = 3\n",
+			config: map[string]interface{}{
+				"name":       "Fan",
+				"net":        map[string]interface{}{"host": "10.0.0.1", "port": 21000.0},
+				"quoted.key": 1.0,
+				"limits":     map[string]interface{}{"speed": map[string]interface{}{"max": 3.0}},
+			},
+			lines: map[int]map[string]int{0: {"name": 1, "net": 2, "quoted.key": 4, "limits": 5}},
+		},
+		{
+			name:  "tables and inline tables",
+			input: "[net]\nhost = \"10.0.0.1\"\n[net.tls]\nenabled = true\n[limits]\nspeed = { min = 1, max = 3 }\n",
+			config: map[string]interface{}{
+				"net":    map[string]interface{}{"host": "10.0.0.1", "tls": map[string]interface{}{"enabled": true}},
+				"limits": map[string]interface{}{"speed": map[string]interface{}{"min": 1.0, "max": 3.0}},
+			},
+		},
+		{
+			name:  "array of tables",
+			input: "[[device]]\nname = \"Fan\"\nport = 1\n\n[[device]]\nname = \"Lamp\"\n[device.net]\nhost = \"10.0.0.2\"\n",
+			config: []interface{}{
+				map[string]interface{}{"name": "Fan", "port": 1.0},
+				map[string]interface{}{"name": "Lamp", "net": map[string]interface{}{"host": "10.0.0.2"}},
+			},
+			lines: map[int]map[string]int{1: {"name": 2, "port": 3}, 2: {"name": 6}},
+		},
+		{
+			name:  "array of tables with root keys",
+			input: "version = 2\n[[device]]\nname = \"Fan\"\n",
+			config: map[string]interface{}{
+				"version": 2.0,
+				"device":  []interface{}{map[string]interface{}{"name": "Fan"}},
+			},
+		},
+		{
+			name:  "duplicate key",
+			input: "name = \"Fan\"\nname = \"Lamp\"\n",
+			err:   "toml: line 2: duplicate key name",
+		},
+		{
+			name:  "table defined twice",
+			input: "[net]\nport = 1\n[net]\nhost = \"x\"\n",
+			err:   "toml: line 3: table net is defined twice",
+		},
+		{
+			name:  "extend inline table",
+			input: "net = { port = 1 }\n[net]\nhost = \"x\"\n",
+			err:   "table net is already defined inline",
+		},
+		{
+			name:  "missing equals",
+			input: "name \"Fan\"\n",
+			err:   "toml: line 1: expected = after key name",
+		},
+		{
+			name:  "unterminated string",
+			input: "name = \"Fan\n",
+			err:   "toml: line 1",
+		},
+		{
+			name:  "leading zero",
+			input: "port = 021\n",
+			err:   "toml: line 1: invalid number",
+		},
+	})
+}
@@ -1,125 +1,176 @@
 package json_configs
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
 )
 
+// Parameter values are compared for conflicts within each config layer
+// - remain is set for a key captured by the remain field, rather than a field name
+type paramLayer struct {
+	name   string
+	layer  int
+	remain bool
+}
+
+// Parameter values are compared in canonical form, with JSON type if StrictEquality is set
+type paramValue struct {
+	value    string
+	jsonType string
+}
+
+// Add a location to the list for a parameter value, by parameter and layer
+func addParamValue(elementParamValuesMap map[paramLayer]map[paramValue][]string, layerKey paramLayer,
+	value paramValue, location string) {
+	paramValuesMap, ok := elementParamValuesMap[layerKey]
+	if !ok {
+		paramValuesMap = make(map[paramValue][]string)
+		elementParamValuesMap[layerKey] = paramValuesMap
+	}
+	paramValuesMap[value] = append(paramValuesMap[value], location)
+}
+
+// Value of a key captured by the remain field, as JSON unless it is a string
+func remainValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
 // Check data object (st) fields for any conflicting result map values
 func validateParameters(st reflect.Type, parsedMap ParsedMap, errList *[]string) {
 	var filenames []string
-	var elementId, key, filename, tagName, paramName, paramValue string
+	var elementId, key, filename string
 	var parsedArr []Parsed
-	var paramValuesMap map[string][]string
 	var v interface{}
 	var i int
 	var ok bool
 
-	// Maps for json tag names <-> param names, and valid param names
-	tag2param := make(map[string]string)
-	param2tag := make(map[string]string)
-	validparam := make(map[string]bool)
-	for i = 0; i < st.NumField(); i++ {
-		param := st.Field(i)
-		tagName, ok = param.Tag.Lookup("json")
-		if ok {
-			tag2param[tagName] = param.Name
-			param2tag[param.Name] = tagName
-		}
-		validparam[param.Name] = true
-	}
+	// Keys for each field, including aliases
+	fields := newFieldKeys(st)
+
+	// Keys not used by other fields are captured by a remain field, if there is one
+	remain := remainField(st)
 
 	// Validate parameters for each element
 	for elementId, parsedArr = range parsedMap {
 
-		// make list of values for each parameter, with filenames found in
-		elementParamValuesMap := make(map[string]map[string][]string)
+		// make list of values for each parameter and layer, with filenames found in
+		elementParamValuesMap := make(map[paramLayer]map[paramValue][]string)
 
 		// make map of parameter names, with filenames found in, to see what isn't used
 		unusedParamMap := make(map[string][]string)
 
 		// check across all files parsed
 		for _, parsed := range parsedArr {
-			if parsed.Position == 0 {
-				filename = parsed.DistinctName
-			} else {
-				filename = fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
-			}
+			filename = parsed.Location()
 
 			// load elementParamValuesMap to identify possible conflicting values
+			// - each key setting a field is checked, so two aliases in one element can conflict
+			values := fields.elementValues(parsed.ElementMap)
 			for i = 0; i < st.NumField(); i++ {
 				param := st.Field(i)
-				// lookup in config dataMap by tag name first, then by param name
-				tagName, ok = param2tag[param.Name]
-				if ok {
-					v, ok = parsed.ElementMap[tagName]
+				if _, removed := param.Tag.Lookup("removed"); removed {
+					continue
 				}
-				if !ok {
-					v, ok = parsed.ElementMap[param.Name]
-				}
-				if ok {
-					paramValue = fmt.Sprintf("%v", v)
+				for _, kv := range values[i] {
+					v = kv.value
 
-					// make list all filenames for each parameter value
-					paramValuesMap, ok = elementParamValuesMap[param.Name]
-					if !ok {
-						paramValuesMap = make(map[string][]string)
-						filenames = []string{filename}
-					} else {
-						filenames, ok = paramValuesMap[paramValue]
-						if ok {
-							filenames = append(filenames, filename)
-						} else {
-							filenames = []string{filename}
-						}
+					// null unsets a value, so it doesn't conflict
+					if v == nil {
+						continue
+					}
+
+					// compare values decoded into the field type, and optionally by JSON type
+					// - a value that doesn't decode is reported by parseConfig(), not as a conflict
+					canonical, err := canonicalValue(param, v)
+					if err != nil {
+						continue
+					}
+					value := paramValue{value: canonical}
+					if StrictEquality {
+						value.jsonType = jsonType(v)
 					}
-					paramValuesMap[paramValue] = filenames
-					elementParamValuesMap[param.Name] = paramValuesMap
+					location := filename
+					if len(values[i]) > 1 {
+						location = fmt.Sprintf("%s key %s", filename, kv.key)
+					}
+
+					// make list all filenames for each parameter value, later layers override so don't conflict
+					addParamValue(elementParamValuesMap, paramLayer{name: param.Name, layer: parsed.Layer}, value, location)
 				}
 			}
 
 			// load unusedParamMap to identify possible unused parameters
-			for key, _ = range parsed.ElementMap {
-				// lookup element key by tag name first
-				paramName, ok = tag2param[key]
-				if !ok {
-					paramName = key
+			for key, v = range parsed.ElementMap {
+				if key == ExtendsKey {
+					continue
 				}
-				if !validparam[paramName] {
-					filenames, ok = unusedParamMap[paramName]
+				if _, _, ok = fields.lookup(key); ok {
+					continue
+				}
+				if remain < 0 {
+					filenames, ok = unusedParamMap[key]
 					if ok {
 						filenames = append(filenames, filename)
 					} else {
 						filenames = []string{filename}
 					}
-					unusedParamMap[paramName] = filenames
+					unusedParamMap[key] = filenames
+					continue
+				}
+
+				// keys captured by the remain field conflict like fields, null unsets a value
+				if v == nil {
+					continue
+				}
+				value := paramValue{value: remainValue(v)}
+				if StrictEquality {
+					value.jsonType = jsonType(v)
 				}
+				addParamValue(elementParamValuesMap, paramLayer{name: key, layer: parsed.Layer, remain: true}, value, filename)
 			}
 		}
 
 		// List errors for conflicting values
-		for paramName, paramValuesMap = range elementParamValuesMap {
+		for layerKey, paramValuesMap := range elementParamValuesMap {
 			// if there are more than one value, it means settings conflict
 			if len(paramValuesMap) > 1 {
 				var conflicts []string
-				for paramValue, filenames = range paramValuesMap {
-					conflicts = append(conflicts, fmt.Sprintf("%q [%s]",
-						paramValue, strings.Join(filenames, ",")))
+				for value, filenames := range paramValuesMap {
+					if len(value.jsonType) > 0 {
+						conflicts = append(conflicts, fmt.Sprintf("%q (%s) [%s]",
+							value.value, value.jsonType, strings.Join(filenames, ",")))
+					} else {
+						conflicts = append(conflicts, fmt.Sprintf("%q [%s]",
+							value.value, strings.Join(filenames, ",")))
+					}
 				}
 				*errList = append(*errList, fmt.Sprintf("settings for %s conflict, parameter %s: %s",
-					elementId, paramName, strings.Join(conflicts, " != ")))
+					elementId, layerKey.name, strings.Join(conflicts, " != ")))
 			}
 		}
 
 		// List  errors for unused parameters
-		for paramName, filenames = range unusedParamMap {
+		for paramName, filenames := range unusedParamMap {
+			// suggest a close match, for a typo or casing difference
+			hint := ""
+			if suggestion := suggestParam(st, paramName); len(suggestion) > 0 {
+				hint = fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
 			if len(filenames) == 1 {
-				*errList = append(*errList, fmt.Sprintf("unused setting for %s, parameter %s [%s]",
-					elementId, paramName, filenames[0]))
+				*errList = append(*errList, fmt.Sprintf("unused setting for %s, parameter %s%s [%s]",
+					elementId, paramName, hint, filenames[0]))
 			} else {
-				*errList = append(*errList, fmt.Sprintf("unused settings for %s, parameter %s: %d occurences [%s]",
-					elementId, paramName, len(filenames), strings.Join(filenames, ",")))
+				*errList = append(*errList, fmt.Sprintf("unused settings for %s, parameter %s%s: %d occurences [%s]",
+					elementId, paramName, hint, len(filenames), strings.Join(filenames, ",")))
 			}
 		}
 	}
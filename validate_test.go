@@ -0,0 +1,72 @@
+package json_configs
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestValidateParameters(t *testing.T) {
+	type validateDevice struct {
+		Name    string        `json:"name"`
+		Port    int           `json:"port"`
+		Timeout time.Duration `json:"timeout"`
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		errs []string // each error starts with the string
+	}{
+		{
+			name: "equal after decoding",
+			a:    `{"name": "Fan", "port": 1, "timeout": "5s"}`,
+			b:    `{"name": "Fan", "port": "1", "timeout": "5000ms"}`,
+		},
+		{
+			name: "conflict",
+			a:    `{"name": "Fan", "port": 1}`,
+			b:    `{"name": "Fan", "port": 2}`,
+			errs: []string{`settings for Fan conflict, parameter Port: `},
+		},
+		{
+			name: "invalid value is not a conflict",
+			a:    `{"name": "Fan", "port": 1}`,
+			b:    `{"name": "Fan", "port": "1.0"}`,
+			errs: []string{"setting for Fan invalid, parameter Port: integer 1.0 [b.json]"},
+		},
+		{
+			name: "null is not a conflict",
+			a:    `{"name": "Fan", "port": 1}`,
+			b:    `{"name": "Fan", "port": null}`,
+			errs: []string{"setting for Fan invalid, parameter Port: null for int [b.json]"},
+		},
+		{
+			name: "unused",
+			a:    `{"name": "Fan", "speed": 1}`,
+			b:    `{"name": "Fan"}`,
+			errs: []string{"unused setting for Fan, parameter speed [a.json]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var device validateDevice
+			fsys := fstest.MapFS{"a.json": {Data: []byte(test.a)}, "b.json": {Data: []byte(test.b)}}
+			_, err := ReadConfigFilesFS(fsys, &device, "Name", "a.json", "b.json")
+			var errs []string
+			if err != nil {
+				errs = strings.Split(err.Error(), "\n")
+			}
+			if len(errs) != len(test.errs) {
+				t.Fatalf("errors:\n got  %q\n want %q", errs, test.errs)
+			}
+			for i := range errs {
+				if !strings.HasPrefix(errs[i], test.errs[i]) {
+					t.Errorf("errors:\n got  %q\n want %q", errs, test.errs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,660 @@
+package json_configs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YAMLDecoder parses YAML config files, the common block and flow styles of YAML 1.2
+// - A mapping is a single element, and a sequence of mappings is an array of elements
+// - Several documents separated by "---" are also an array of elements
+// - Anchors, aliases and complex keys are not supported
+type YAMLDecoder struct{}
+
+// A line of YAML structure, with comments removed
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	raw   []string
+	lines []yamlLine
+	i     int
+
+	// Keys of the mappings at elementDepth are recorded in doc.Lines, at position
+	doc          *Document
+	elementDepth int
+	position     int
+}
+
+var (
+	yamlIntRegexp   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatRegexp = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+func (YAMLDecoder) Decode(b []byte) (doc Document, err error) {
+	var documents [][]yamlLine
+	var values []interface{}
+	var lines []yamlLine
+	var v interface{}
+	var text string
+	var i, n int
+
+	raw := strings.Split(strings.Replace(strings.TrimPrefix(string(b), "\ufeff"), "\r\n", "\n", -1), "\n")
+	p := yamlParser{raw: raw, doc: &doc}
+
+	// Split into documents of structural lines
+	for i = range raw {
+		text = strings.TrimRight(raw[i], " \t")
+		if text == "---" || strings.HasPrefix(text, "--- ") || text == "..." {
+			if len(lines) > 0 {
+				documents = append(documents, lines)
+			}
+			lines = nil
+			if text = strings.TrimSpace(strings.TrimPrefix(text, "---")); len(text) > 0 && text != "..." {
+				lines = append(lines, yamlLine{text: yamlStripComment(text), number: i + 1})
+			}
+			continue
+		}
+		if len(documents) == 0 && len(lines) == 0 && strings.HasPrefix(text, "%") {
+			// directive, such as %YAML 1.2
+			continue
+		}
+		n = len(text) - len(strings.TrimLeft(text, " "))
+		if strings.HasPrefix(text[n:], "\t") {
+			err = fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+			return
+		}
+		if text = yamlStripComment(text[n:]); len(text) == 0 {
+			continue
+		}
+		lines = append(lines, yamlLine{indent: n, text: text, number: i + 1})
+	}
+	if len(lines) > 0 {
+		documents = append(documents, lines)
+	}
+
+	for _, lines = range documents {
+		p.lines = lines
+		p.i = 0
+		p.elementDepth = 0
+		p.position = 0
+		if len(documents) > 1 {
+			p.position = len(values) + 1
+		}
+		if v, err = p.parseNode(lines[0].indent, 0); err != nil {
+			return
+		}
+		if p.i < len(p.lines) {
+			err = p.errorf(p.lines[p.i], "unexpected content")
+			return
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 1 {
+		doc.Config = values[0]
+	} else if len(values) > 1 {
+		doc.Config = values
+	}
+	return
+}
+
+func (p *yamlParser) errorf(line yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", line.number, fmt.Sprintf(format, args...))
+}
+
+// Parse the node starting at the current line
+func (p *yamlParser) parseNode(indent, depth int) (v interface{}, err error) {
+	line := p.lines[p.i]
+	if yamlIsSeqItem(line.text) {
+		return p.parseSequence(line.indent, depth)
+	}
+	if _, _, ok := yamlSplitKey(line.text); ok {
+		return p.parseMapping(line.indent, depth)
+	}
+	p.i++
+	return p.parseInline(indent, line.text, line)
+}
+
+// Parse a block mapping, of keys at 'indent'
+func (p *yamlParser) parseMapping(indent, depth int) (m map[string]interface{}, err error) {
+	var v interface{}
+
+	m = make(map[string]interface{})
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			err = p.errorf(line, "unexpected indentation")
+			return
+		}
+		key, rest, ok := yamlSplitKey(line.text)
+		if !ok {
+			if yamlIsSeqItem(line.text) {
+				break
+			}
+			err = p.errorf(line, "expected a mapping key")
+			return
+		}
+		if _, ok = m[key]; ok {
+			err = p.errorf(line, "duplicate key %s", key)
+			return
+		}
+		if depth == p.elementDepth {
+			p.doc.setLine(p.position, key, line.number)
+		}
+		p.i++
+
+		// A sequence can be the value of a key at the same indent
+		if len(rest) == 0 && p.i < len(p.lines) && p.lines[p.i].indent == indent && yamlIsSeqItem(p.lines[p.i].text) {
+			v, err = p.parseSequence(indent, depth+1)
+		} else {
+			v, err = p.parseValue(indent, rest, line, depth+1)
+		}
+		if err != nil {
+			return
+		}
+		m[key] = v
+	}
+	return
+}
+
+// Parse a block sequence, of items at 'indent'
+func (p *yamlParser) parseSequence(indent, depth int) (seq []interface{}, err error) {
+	var v interface{}
+	var start int
+
+	seq = []interface{}{}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent < indent || (line.indent == indent && !yamlIsSeqItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			err = p.errorf(line, "unexpected indentation")
+			return
+		}
+
+		// Items of a top level sequence are elements
+		if depth == 0 {
+			p.elementDepth = 1
+			p.position = len(seq) + 1
+		}
+
+		start = 1 + len(line.text[1:]) - len(strings.TrimLeft(line.text[1:], " "))
+		rest := line.text[start:]
+		if _, _, ok := yamlSplitKey(rest); ok || yamlIsSeqItem(rest) {
+			// A compact mapping or sequence, such as "- name: Fan", continues at the column of the item
+			p.lines[p.i] = yamlLine{indent: indent + start, text: rest, number: line.number}
+			v, err = p.parseNode(indent+start, depth+1)
+		} else {
+			p.i++
+			v, err = p.parseValue(indent, rest, line, depth+1)
+		}
+		if err != nil {
+			return
+		}
+		seq = append(seq, v)
+	}
+	return
+}
+
+// Parse the value after a key or sequence item, inline or as a nested block
+func (p *yamlParser) parseValue(indent int, rest string, line yamlLine, depth int) (v interface{}, err error) {
+	if len(rest) == 0 {
+		if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+			return p.parseNode(p.lines[p.i].indent, depth)
+		}
+		return nil, nil
+	}
+	return p.parseInline(indent, rest, line)
+}
+
+// Parse a value written on the line, with any continuation lines
+func (p *yamlParser) parseInline(indent int, text string, line yamlLine) (v interface{}, err error) {
+	var tag string
+
+	// A tag, where only !!str changes the value
+	if text[0] == '!' {
+		if i := strings.IndexByte(text, ' '); i > 0 {
+			tag, text = text[:i], strings.TrimSpace(text[i:])
+		} else {
+			tag, text = text, ""
+		}
+	}
+
+	switch {
+	case len(text) == 0:
+		return nil, nil
+	case text[0] == '&' || text[0] == '*':
+		err = p.errorf(line, "anchors and aliases are not supported")
+		return
+	case text[0] == '|' || text[0] == '>':
+		return p.parseBlockScalar(indent, text, line)
+	case text[0] == '[' || text[0] == '{':
+		// Flow collections continue on the following lines until closed, such as a ] at the indent of the key
+		for !yamlBalanced(text) && p.i < len(p.lines) && p.lines[p.i].indent >= indent {
+			text += " " + p.lines[p.i].text
+			p.i++
+		}
+		pos := 0
+		if v, err = yamlParseFlow(text, &pos); err == nil && strings.TrimSpace(text[pos:]) != "" {
+			err = fmt.Errorf("unexpected %q", strings.TrimSpace(text[pos:]))
+		}
+		if err != nil {
+			err = p.errorf(line, "%v", err)
+		}
+		return
+	case text[0] == '"' || text[0] == '\'':
+		if v, err = yamlQuoted(text); err != nil {
+			err = p.errorf(line, "%v", err)
+		}
+		return
+	}
+
+	// Plain scalars can continue on more indented lines, which can't be mapping keys
+	for p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		if _, _, ok := yamlSplitKey(p.lines[p.i].text); ok {
+			err = p.errorf(p.lines[p.i], "unexpected indentation")
+			return
+		}
+		text += " " + p.lines[p.i].text
+		p.i++
+	}
+	if tag == "!!str" {
+		return text, nil
+	}
+	return yamlScalar(text), nil
+}
+
+// Parse a literal (|) or folded (>) block scalar from the raw lines following 'line'
+func (p *yamlParser) parseBlockScalar(indent int, header string, line yamlLine) (v interface{}, err error) {
+	var content []string
+	var text string
+	var blockIndent, last, n, i int
+
+	chomp := ""
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = string(c)
+		case c >= '1' && c <= '9':
+			blockIndent = indent + int(c-'0')
+		case c == ' ':
+		default:
+			err = p.errorf(line, "invalid block scalar header %s", header)
+			return
+		}
+	}
+
+	last = line.number
+	for i = line.number; i < len(p.raw); i++ {
+		text = strings.TrimRight(p.raw[i], " \t\r")
+		n = len(text) - len(strings.TrimLeft(text, " "))
+		if len(text) == 0 {
+			content = append(content, "")
+			continue
+		}
+		if blockIndent == 0 {
+			if n <= indent {
+				break
+			}
+			blockIndent = n
+		}
+		if n < blockIndent {
+			break
+		}
+		content = append(content, strings.TrimRight(p.raw[i], "\r")[blockIndent:])
+		last = i + 1
+	}
+	content = content[:last-line.number]
+
+	// Skip the structural lines of the block
+	for p.i < len(p.lines) && p.lines[p.i].number <= last {
+		p.i++
+	}
+
+	if header[0] == '|' {
+		text = strings.Join(content, "\n")
+	} else {
+		// Folded lines are joined by spaces, blank lines become newlines,
+		// and the line breaks around more indented lines are kept
+		text = ""
+		prev := -1
+		for i = range content {
+			if len(content[i]) == 0 {
+				continue
+			}
+			switch {
+			case prev < 0:
+				text += strings.Repeat("\n", i)
+			case strings.HasPrefix(content[i], " ") || strings.HasPrefix(content[prev], " "):
+				text += strings.Repeat("\n", i-prev)
+			case i-prev == 1:
+				text += " "
+			default:
+				text += strings.Repeat("\n", i-prev-1)
+			}
+			text += content[i]
+			prev = i
+		}
+	}
+
+	switch chomp {
+	case "-":
+		text = strings.TrimRight(text, "\n")
+	case "+":
+		text += "\n"
+		for i = last; i < len(p.raw)-1 && len(strings.TrimSpace(p.raw[i])) == 0; i++ {
+			text += "\n"
+		}
+	default:
+		if len(content) > 0 {
+			text = strings.TrimRight(text, "\n") + "\n"
+		}
+	}
+	return text, nil
+}
+
+// Check if a line is a block sequence item
+func yamlIsSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Split a mapping line into key and the rest of the line, such as "host: 192.168.0.10"
+func yamlSplitKey(text string) (key, rest string, ok bool) {
+	var i int
+	var err error
+
+	if len(text) == 0 || strings.ContainsRune("[{?#&*!|>%@`", rune(text[0])) || yamlIsSeqItem(text) {
+		return
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		if i = yamlQuoteEnd(text); i < 0 {
+			return
+		}
+		rest = strings.TrimLeft(text[i+1:], " ")
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return
+		}
+		var v interface{}
+		if v, err = yamlQuoted(text[:i+1]); err != nil {
+			return
+		}
+		return v.(string), strings.TrimSpace(rest[1:]), true
+	}
+	for i = 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return
+}
+
+// Remove a comment from a line, where # starts a comment at the start or after a space, outside quotes
+func yamlStripComment(text string) string {
+	var quote byte
+	var i int
+
+	for i = 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+					i++
+				} else {
+					quote = 0
+				}
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		case c == '"' || c == '\'':
+			// Quotes start a scalar only at the start of a token
+			if j := strings.TrimRight(text[:i], " "); len(j) == 0 || strings.ContainsRune(":-[{,", rune(j[len(j)-1])) {
+				quote = c
+			}
+		}
+	}
+	return strings.TrimRight(text, " \t")
+}
+
+// Index of the closing quote of a quoted scalar at the start of text, or -1
+func yamlQuoteEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// Value of a single or double quoted scalar
+func yamlQuoted(text string) (v interface{}, err error) {
+	var sb strings.Builder
+	var code uint64
+	var size int
+
+	end := yamlQuoteEnd(text)
+	if end < 0 {
+		err = fmt.Errorf("unterminated quoted string")
+		return
+	}
+	if strings.TrimSpace(text[end+1:]) != "" {
+		err = fmt.Errorf("unexpected %q after quoted string", strings.TrimSpace(text[end+1:]))
+		return
+	}
+	if text[0] == '\'' {
+		return strings.Replace(text[1:end], "''", "'", -1), nil
+	}
+
+	s := text[1:end]
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i++; i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '0':
+			sb.WriteByte(0)
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 't', '\t':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			sb.WriteByte(s[i])
+		case 'x', 'u', 'U':
+			size = map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+			if i+size >= len(s) {
+				err = fmt.Errorf("invalid escape \\%c", s[i])
+				return
+			}
+			if code, err = strconv.ParseUint(s[i+1:i+1+size], 16, 32); err != nil {
+				err = fmt.Errorf("invalid escape \\%s", s[i:i+1+size])
+				return
+			}
+			sb.WriteRune(rune(code))
+			i += size
+		default:
+			err = fmt.Errorf("invalid escape \\%c", s[i])
+			return
+		}
+	}
+	return sb.String(), nil
+}
+
+// Value of a plain scalar, with the types of the YAML 1.2 core schema
+func yamlScalar(text string) interface{} {
+	var n int64
+	var f float64
+	var err error
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if strings.HasPrefix(text, "0x") {
+		if n, err = strconv.ParseInt(text[2:], 16, 64); err == nil {
+			return float64(n)
+		}
+	} else if strings.HasPrefix(text, "0o") {
+		if n, err = strconv.ParseInt(text[2:], 8, 64); err == nil {
+			return float64(n)
+		}
+	} else if yamlIntRegexp.MatchString(text) || yamlFloatRegexp.MatchString(text) {
+		if f, err = strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+// Check if the brackets and braces of a flow collection are closed, outside quotes
+func yamlBalanced(text string) bool {
+	var quote byte
+	var depth int
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// Parse a flow collection or scalar at text[*pos], such as [fan, lamp] or {host: 10.0.0.1, port: 21000}
+func yamlParseFlow(text string, pos *int) (v interface{}, err error) {
+	var key interface{}
+	var end int
+
+	skip := func() {
+		for *pos < len(text) && text[*pos] == ' ' {
+			*pos++
+		}
+	}
+	skip()
+	if *pos >= len(text) {
+		err = fmt.Errorf("unexpected end of flow collection")
+		return
+	}
+
+	switch text[*pos] {
+	case '[':
+		seq := []interface{}{}
+		*pos++
+		for {
+			skip()
+			if *pos < len(text) && text[*pos] == ']' {
+				*pos++
+				return seq, nil
+			}
+			if v, err = yamlParseFlow(text, pos); err != nil {
+				return
+			}
+			seq = append(seq, v)
+			skip()
+			if *pos < len(text) && text[*pos] == ',' {
+				*pos++
+			} else if *pos >= len(text) || text[*pos] != ']' {
+				err = fmt.Errorf("expected , or ] in flow sequence")
+				return
+			}
+		}
+	case '{':
+		m := make(map[string]interface{})
+		*pos++
+		for {
+			skip()
+			if *pos < len(text) && text[*pos] == '}' {
+				*pos++
+				return m, nil
+			}
+			if key, err = yamlParseFlow(text, pos); err != nil {
+				return
+			}
+			skip()
+			v = nil
+			if *pos < len(text) && text[*pos] == ':' {
+				*pos++
+				skip()
+				if *pos < len(text) && text[*pos] != ',' && text[*pos] != '}' {
+					if v, err = yamlParseFlow(text, pos); err != nil {
+						return
+					}
+				}
+			}
+			m[fmt.Sprintf("%v", key)] = v
+			skip()
+			if *pos < len(text) && text[*pos] == ',' {
+				*pos++
+			} else if *pos >= len(text) || text[*pos] != '}' {
+				err = fmt.Errorf("expected , or } in flow mapping")
+				return
+			}
+		}
+	case '"', '\'':
+		if end = yamlQuoteEnd(text[*pos:]); end < 0 {
+			err = fmt.Errorf("unterminated quoted string")
+			return
+		}
+		v, err = yamlQuoted(text[*pos : *pos+end+1])
+		*pos += end + 1
+		return
+	}
+
+	// Plain scalar, ending at a flow indicator or ": "
+	start := *pos
+	for *pos < len(text) && !strings.ContainsRune(",[]{}", rune(text[*pos])) &&
+		!(text[*pos] == ':' && (*pos+1 == len(text) || strings.ContainsRune(" ,]}", rune(text[*pos+1])))) {
+		*pos++
+	}
+	return yamlScalar(strings.TrimSpace(text[start:*pos])), nil
+}
@@ -0,0 +1,117 @@
+package json_configs
+
+import (
+	"testing"
+)
+
+func TestYAMLDecoder(t *testing.T) {
+	runDecoderTests(t, YAMLDecoder{}, []decoderTest{
+		{
+			name:  "scalars",
+			input: "name: Fan\nport: 21000\nratio: 0.5\nenabled: true\nhost: ~\nhex: 0x1F\nversion: 1.2.3\n",
+			config: map[string]interface{}{
+				"name": "Fan", "port": 21000.0, "ratio": 0.5, "enabled": true, "host": nil, "hex": 31.0, "version": "1.2.3",
+			},
+			lines: map[int]map[string]int{0: {"name": 1, "port": 2, "ratio": 3, "enabled": 4, "host": 5, "hex": 6, "version": 7}},
+		},
+		{
+			name:  "quoted and comments",
+			input: "# device\nname: 'it''s' # trailing\nhost: \"10.0.0.1\\t# not a comment\"\nport: \"21000\"\ntag: a#b\n",
+			config: map[string]interface{}{
+				"name": "it's", "host": "10.0.0.1\t# not a comment", "port": "21000", "tag": "a#b",
+			},
+			lines: map[int]map[string]int{0: {"name": 2, "host": 3, "port": 4, "tag": 5}},
+		},
+		{
+			name:  "nested mapping and sequence",
+			input: "name: Fan\nnet:\n  host: 10.0.0.1\n  ports:\n    - 80\n    - 443\ntags:\n- a\n- b\n",
+			config: map[string]interface{}{
+				"name": "Fan",
+				"net":  map[string]interface{}{"host": "10.0.0.1", "ports": []interface{}{80.0, 443.0}},
+				"tags": []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:  "sequence of mappings",
+			input: "- name: Fan\n  port: 1\n\n- name: Lamp\n  tags: [a, b]\n",
+			config: []interface{}{
+				map[string]interface{}{"name": "Fan", "port": 1.0},
+				map[string]interface{}{"name": "Lamp", "tags": []interface{}{"a", "b"}},
+			},
+			lines: map[int]map[string]int{1: {"name": 1, "port": 2}, 2: {"name": 4, "tags": 5}},
+		},
+		{
+			name:  "flow collections",
+			input: "net: {host: 10.0.0.1, ports: [80, 443], 'quoted key': \"a, b\"}\nempty: []\nnone: {}\n",
+			config: map[string]interface{}{
+				"net": map[string]interface{}{
+					"host": "10.0.0.1", "ports": []interface{}{80.0, 443.0}, "quoted key": "a, b",
+				},
+				"empty": []interface{}{},
+				"none":  map[string]interface{}{},
+			},
+		},
+		{
+			name:  "flow collection over lines",
+			input: "tags: [\n  a,\n  b\n]\nname: Fan\n",
+			config: map[string]interface{}{
+				"tags": []interface{}{"a", "b"}, "name": "Fan",
+			},
+		},
+		{
+			name:  "literal block scalar",
+			input: "script: |\n  line 1\n    indented\n\n  line 3\nname: Fan\n",
+			config: map[string]interface{}{
+				"script": "line 1\n  indented\n\nline 3\n", "name": "Fan",
+			},
+		},
+		{
+			name:  "folded block scalar",
+			input: "desc: >\n  one\n  two\n\n  three\nname: Fan\n",
+			config: map[string]interface{}{
+				"desc": "one two\nthree\n", "name": "Fan",
+			},
+		},
+		{
+			name:  "block scalar chomping",
+			input: "strip: |-\n  a\n  b\n\nkeep: |+\n  a\n\nclip: |\n  a\n\n",
+			config: map[string]interface{}{
+				"strip": "a\nb", "keep": "a\n\n", "clip": "a\n",
+			},
+		},
+		{
+			name:  "multiple documents",
+			input: "%YAML 1.2\n---\nname: Fan\n---\nname: Lamp\nport: 2\n...\n",
+			config: []interface{}{
+				map[string]interface{}{"name": "Fan"},
+				map[string]interface{}{"name": "Lamp", "port": 2.0},
+			},
+			lines: map[int]map[string]int{1: {"name": 3}, 2: {"name": 5, "port": 6}},
+		},
+		{
+			name:   "empty leading document",
+			input:  "---\n# nothing\n---\nname: Fan\n",
+			config: map[string]interface{}{"name": "Fan"},
+		},
+		{
+			name:  "tab indentation",
+			input: "net:\n\thost: 10.0.0.1\n",
+			err:   "yaml: line 2: tabs are not allowed",
+		},
+		{
+			name:  "unclosed flow",
+			input: "tags: [a, b\n",
+			err:   "yaml: line 1",
+		},
+		{
+			name:  "bad indentation",
+			input: "name: Fan\n  port: 1\n",
+			err:   "yaml: line 2",
+		},
+		{
+			name:  "duplicate key",
+			input: "name: Fan\nname: Lamp\n",
+			err:   "yaml: line 2",
+		},
+	})
+}